@@ -46,6 +46,12 @@ func main() {
 			ctx, terminate := context.WithCancel(shutdownCtx)
 			defer terminate()
 
+			go func() {
+				if err := agentOptions.RunMetricsServer(ctx); err != nil {
+					klog.Fatalf("failed to run agent metrics server, %v", err)
+				}
+			}()
+
 			// starting agent firstly to request the hub kubeconfig
 			go func() {
 				klog.Info("starting the controlplane agent")
@@ -71,35 +77,44 @@ func main() {
 
 	flags := cmd.Flags()
 
+	// the defaults of the policy agent flags are the same as the defaults of the addons config
+	policyAgentConfig := &agentOptions.AddOnsConfig.Policy.Agent
+
 	flags.UintVar(
-		&agentOptions.Frequency,
+		&policyAgentConfig.Frequency,
 		"update-frequency",
-		60,
+		policyAgentConfig.Frequency,
 		"The status update frequency (in seconds) of a mutation policy",
 	)
 
 	flags.Uint8Var(
-		&agentOptions.DecryptionConcurrency,
+		&policyAgentConfig.DecryptionConcurrency,
 		"decryption-concurrency",
-		5,
+		policyAgentConfig.DecryptionConcurrency,
 		"The max number of concurrent policy template decryptions",
 	)
 
 	flags.Uint8Var(
-		&agentOptions.EvaluationConcurrency,
+		&policyAgentConfig.EvaluationConcurrency,
 		"evaluation-concurrency",
-		// Set a low default to not add too much load to the Kubernetes API server in resource constrained deployments.
-		2,
+		policyAgentConfig.EvaluationConcurrency,
 		"The max number of concurrent configuration policy evaluations",
 	)
 
 	flags.BoolVar(
-		&agentOptions.EnableMetrics,
+		&policyAgentConfig.EnableMetrics,
 		"enable-metrics",
-		false,
+		policyAgentConfig.EnableMetrics,
 		"Disable custom metrics collection",
 	)
 
+	flags.StringVar(
+		&agentOptions.MetricsBindAddress,
+		"metrics-bind-address",
+		":8383",
+		"The address the agent metrics (/metrics) and health probes (/healthz and /readyz) are served on, set it to 0 to disable it",
+	)
+
 	agentOptions.AddFlags(flags)
 
 	os.Exit(cli.Run(cmd))
@@ -22,6 +22,7 @@ import (
 	"open-cluster-management.io/multicluster-controlplane/pkg/servers"
 	"open-cluster-management.io/multicluster-controlplane/pkg/servers/options"
 
+	"github.com/stolostron/multicluster-controlplane/pkg/config"
 	controller "github.com/stolostron/multicluster-controlplane/pkg/controllers"
 	"github.com/stolostron/multicluster-controlplane/pkg/controllers/selfmanagement"
 	"github.com/stolostron/multicluster-controlplane/pkg/feature"
@@ -58,9 +59,14 @@ func main() {
 				return err
 			}
 
+			addOnsConfig, err := config.LoadAddOnsConfig(options.ControlplaneConfigDir)
+			if err != nil {
+				return err
+			}
+
 			server := servers.NewServer(*options)
-			server.AddController("next-gen-controlplane-controllers", controller.InstallControllers)
-			server.AddController("next-gen-controlplane-self-management", selfmanagement.InstallControllers(options))
+			server.AddController("next-gen-controlplane-controllers", controller.InstallControllers(addOnsConfig))
+			server.AddController("next-gen-controlplane-self-management", selfmanagement.InstallControllers(options, addOnsConfig))
 
 			return server.Start(stopChan)
 		},
@@ -0,0 +1,151 @@
+// Copyright Contributors to the Open Cluster Management project
+package addon
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/component-base/featuregate"
+
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/stolostron/multicluster-controlplane/pkg/config"
+)
+
+// AddOn is an in-process addon of the controlplane, an addon declares its controlplane (hub) side setup by
+// implementing the HubAddOn, and its agent side setup by implementing the AgentAddOn.
+type AddOn interface {
+	// Name returns the unique name of the addon.
+	Name() string
+
+	// FeatureGate returns the feature gate that enables the addon, the addon is always enabled if it is empty.
+	FeatureGate() featuregate.Feature
+}
+
+// HubAddOn is an addon that runs controllers in the controlplane.
+type HubAddOn interface {
+	AddOn
+
+	// HubCRDs returns the CRD files that are required by the addon in the controlplane.
+	HubCRDs() (fs embed.FS, files []string)
+
+	// SetupHub sets up the addon controllers with the controller-runtime manager of the controlplane. A failed setup
+	// is retried with the same HubContext, the addon runs its setup with the HubContext.SetupSteps to not set up the
+	// succeeded steps twice.
+	SetupHub(ctx context.Context, hubContext *HubContext) error
+}
+
+// AgentAddOn is an addon that runs controllers in the controlplane agent.
+type AgentAddOn interface {
+	AddOn
+
+	// AgentCRDs returns the CRD files that are required by the addon on the managed (spoke) cluster and the
+	// hosting cluster.
+	AgentCRDs() (fs embed.FS, spokeFiles, hostingFiles []string)
+
+	// SetupAgent starts the addon controllers in the agent.
+	SetupAgent(ctx context.Context, agentContext *AgentContext) error
+}
+
+// HubContext is passed to the HubAddOn when it is set up.
+type HubContext struct {
+	// Config is the addons config of the controlplane.
+	Config  *config.AddOnsConfig
+	Manager manager.Manager
+	// KubeConfig is the loopback config of the controlplane.
+	KubeConfig    *rest.Config
+	KubeClient    kubernetes.Interface
+	DynamicClient dynamic.Interface
+	// SetupSteps records the succeeded setup steps of the addon with the Manager.
+	SetupSteps *SetupSteps
+}
+
+// AgentContext is passed to the AgentAddOn when it is set up.
+type AgentContext struct {
+	Scheme                *runtime.Scheme
+	ClusterName           string
+	SelfManagementEnabled bool
+	// Config is the addons config of the agent.
+	Config *config.AddOnsConfig
+	// HubKubeConfig is the config of the controlplane.
+	HubKubeConfig *rest.Config
+	// HostingKubeConfig is the config of the cluster that the agent is running on, in the hosted mode it is for the
+	// management cluster, in the default mode it is for the managed cluster.
+	HostingKubeConfig *rest.Config
+	// SpokeKubeConfig is always the config of the managed cluster.
+	SpokeKubeConfig             *rest.Config
+	SpokeRestMapper             meta.RESTMapper
+	SpokeKubeInformerFactory    informers.SharedInformerFactory
+	SpokeClusterInformerFactory clusterinformers.SharedInformerFactory
+	// AddHealthzCheck adds a liveness check to the agent, the agent is restarted once the check fails.
+	AddHealthzCheck func(name string, check healthz.Checker)
+	// HostedMode is true if the agent is running on the management cluster in the hosted mode.
+	HostedMode bool
+}
+
+var (
+	lock   sync.RWMutex
+	addOns []AddOn
+)
+
+// Register registers an addon to the registry, the addons are set up in the order of the registration. It panics
+// if an addon with the same name is already registered.
+func Register(addOn AddOn) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	for _, registered := range addOns {
+		if registered.Name() == addOn.Name() {
+			panic(fmt.Sprintf("the addon %q is already registered", addOn.Name()))
+		}
+	}
+
+	addOns = append(addOns, addOn)
+}
+
+// HubAddOns returns the registered addons that run in the controlplane.
+func HubAddOns() []HubAddOn {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	hubAddOns := []HubAddOn{}
+	for _, addOn := range addOns {
+		if hubAddOn, ok := addOn.(HubAddOn); ok {
+			hubAddOns = append(hubAddOns, hubAddOn)
+		}
+	}
+	return hubAddOns
+}
+
+// AgentAddOns returns the registered addons that run in the controlplane agent.
+func AgentAddOns() []AgentAddOn {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	agentAddOns := []AgentAddOn{}
+	for _, addOn := range addOns {
+		if agentAddOn, ok := addOn.(AgentAddOn); ok {
+			agentAddOns = append(agentAddOns, agentAddOn)
+		}
+	}
+	return agentAddOns
+}
+
+// Enabled returns true if the feature gate of the addon is enabled by the given feature gates.
+func Enabled(addOn AddOn, gates featuregate.FeatureGate) bool {
+	if len(addOn.FeatureGate()) == 0 {
+		return true
+	}
+	return gates.Enabled(addOn.FeatureGate())
+}
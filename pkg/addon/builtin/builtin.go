@@ -0,0 +1,13 @@
+// Copyright Contributors to the Open Cluster Management project
+
+// Package builtin registers the built-in addons of the controlplane.
+package builtin
+
+import (
+	"github.com/stolostron/multicluster-controlplane/pkg/addon"
+)
+
+func init() {
+	addon.Register(&managedClusterInfoAddOn{})
+	addon.Register(&policyAddOn{})
+}
@@ -0,0 +1,61 @@
+// Copyright Contributors to the Open Cluster Management project
+package builtin
+
+import (
+	"context"
+	"embed"
+
+	"k8s.io/component-base/featuregate"
+
+	"github.com/stolostron/multicluster-controlplane/pkg/addon"
+	agentaddons "github.com/stolostron/multicluster-controlplane/pkg/agent/addons"
+	agentmanifests "github.com/stolostron/multicluster-controlplane/pkg/agent/addons/manifests"
+	hubaddons "github.com/stolostron/multicluster-controlplane/pkg/controllers/addons"
+	hubmanifests "github.com/stolostron/multicluster-controlplane/pkg/controllers/manifests"
+	"github.com/stolostron/multicluster-controlplane/pkg/feature"
+)
+
+type managedClusterInfoAddOn struct{}
+
+var _ addon.HubAddOn = &managedClusterInfoAddOn{}
+var _ addon.AgentAddOn = &managedClusterInfoAddOn{}
+
+func (a *managedClusterInfoAddOn) Name() string {
+	return "managed-cluster-info"
+}
+
+func (a *managedClusterInfoAddOn) FeatureGate() featuregate.Feature {
+	return feature.ManagedClusterInfo
+}
+
+func (a *managedClusterInfoAddOn) HubCRDs() (embed.FS, []string) {
+	return hubmanifests.CRDFiles, []string{
+		"crds/internal.open-cluster-management.io_managedclusterinfos.crd.yaml",
+		"crds/view.open-cluster-management.io_managedclusterviews.crd.yaml",
+		"crds/action.open-cluster-management.io_managedclusteractions.crd.yaml",
+	}
+}
+
+func (a *managedClusterInfoAddOn) SetupHub(ctx context.Context, hubContext *addon.HubContext) error {
+	return hubContext.SetupSteps.Run(ctx, func(ctx context.Context) error {
+		return hubaddons.SetupManagedClusterInfoWithManager(ctx, hubContext.Manager, hubContext.Config.ManagedClusterInfo)
+	})
+}
+
+func (a *managedClusterInfoAddOn) AgentCRDs() (embed.FS, []string, []string) {
+	return agentmanifests.AgentCRDFiles, nil, nil
+}
+
+func (a *managedClusterInfoAddOn) SetupAgent(ctx context.Context, agentContext *addon.AgentContext) error {
+	return agentaddons.StartManagedClusterInfoAgent(
+		ctx,
+		agentContext.ClusterName,
+		agentContext.SelfManagementEnabled,
+		agentContext.Config.ResyncPeriod.Duration,
+		agentContext.HubKubeConfig,
+		agentContext.SpokeKubeConfig,
+		agentContext.SpokeRestMapper,
+		agentContext.SpokeKubeInformerFactory,
+		agentContext.SpokeClusterInformerFactory,
+	)
+}
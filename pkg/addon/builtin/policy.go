@@ -0,0 +1,133 @@
+// Copyright Contributors to the Open Cluster Management project
+package builtin
+
+import (
+	"context"
+	"embed"
+
+	"k8s.io/component-base/featuregate"
+	"k8s.io/klog/v2"
+
+	"open-cluster-management.io/multicluster-controlplane/pkg/features"
+
+	"github.com/stolostron/multicluster-controlplane/pkg/addon"
+	agentaddons "github.com/stolostron/multicluster-controlplane/pkg/agent/addons"
+	agentmanifests "github.com/stolostron/multicluster-controlplane/pkg/agent/addons/manifests"
+	hubaddons "github.com/stolostron/multicluster-controlplane/pkg/controllers/addons"
+	"github.com/stolostron/multicluster-controlplane/pkg/controllers/addons/placementrule"
+	hubmanifests "github.com/stolostron/multicluster-controlplane/pkg/controllers/manifests"
+	"github.com/stolostron/multicluster-controlplane/pkg/feature"
+)
+
+type policyAddOn struct{}
+
+var _ addon.HubAddOn = &policyAddOn{}
+var _ addon.AgentAddOn = &policyAddOn{}
+
+func (a *policyAddOn) Name() string {
+	return "configuration-policy"
+}
+
+func (a *policyAddOn) FeatureGate() featuregate.Feature {
+	return feature.ConfigurationPolicy
+}
+
+func (a *policyAddOn) HubCRDs() (embed.FS, []string) {
+	return hubmanifests.CRDFiles, []string{
+		"crds/apps.open-cluster-management.io_placementrules.crd.yaml",
+		"crds/policy.open-cluster-management.io_placementbindings.crd.yaml",
+		"crds/policy.open-cluster-management.io_policies.crd.yaml",
+		"crds/policy.open-cluster-management.io_policyautomations.crd.yaml",
+		"crds/policy.open-cluster-management.io_policysets.crd.yaml",
+	}
+}
+
+func (a *policyAddOn) SetupHub(ctx context.Context, hubContext *addon.HubContext) error {
+	steps := []addon.SetupStep{
+		// the policies that are bound to the placementrules are propagated by the decisions of the placementrules
+		func(ctx context.Context) error {
+			return placementrule.SetupWithManager(
+				hubContext.Manager,
+				features.DefaultControlplaneMutableFeatureGate.Enabled(feature.ManagedClusterInfo),
+			)
+		},
+	}
+	steps = append(steps, hubaddons.PolicySetupSteps(
+		hubContext.Manager,
+		hubContext.KubeConfig,
+		hubContext.KubeClient,
+		hubContext.DynamicClient,
+		hubContext.Config.Policy,
+	)...)
+
+	return hubContext.SetupSteps.Run(ctx, steps...)
+}
+
+func (a *policyAddOn) AgentCRDs() (embed.FS, []string, []string) {
+	hostingCRDFiles := []string{
+		"crds/policy.open-cluster-management.io_configurationpolicies.crd.yaml",
+		"crds/policy.open-cluster-management.io_policies.crd.yaml",
+	}
+	if features.DefaultAgentMutableFeatureGate.Enabled(feature.CertificatePolicy) {
+		hostingCRDFiles = append(hostingCRDFiles, "crds/policy.open-cluster-management.io_certificatepolicies.crd.yaml")
+	}
+	return agentmanifests.AgentCRDFiles, nil, hostingCRDFiles
+}
+
+func (a *policyAddOn) SetupAgent(ctx context.Context, agentContext *addon.AgentContext) error {
+	// the setup is retried with new managers when it fails, so each attempt runs with its own context that is
+	// canceled on the failure to stop the goroutines that are started by the attempt
+	ctx, cancel := context.WithCancel(ctx)
+	succeeded := false
+	defer func() {
+		if !succeeded {
+			cancel()
+		}
+	}()
+
+	hubManager, err := agentaddons.NewPolicyHubManager(
+		agentContext.Scheme, agentContext.HubKubeConfig, agentContext.ClusterName)
+	if err != nil {
+		return err
+	}
+
+	hostingManager, err := agentaddons.NewPolicyHostingManager(
+		agentContext.Scheme, agentContext.HostingKubeConfig, agentContext.ClusterName)
+	if err != nil {
+		return err
+	}
+
+	if err := agentaddons.StartPolicyAgent(
+		ctx,
+		agentContext.Scheme,
+		agentContext.ClusterName,
+		agentContext.HubKubeConfig,
+		agentContext.HostingKubeConfig,
+		agentContext.SpokeKubeConfig,
+		agentContext.HostedMode,
+		hubManager,
+		hostingManager,
+		&agentContext.Config.Policy.Agent,
+		agentContext.AddHealthzCheck,
+	); err != nil {
+		return err
+	}
+
+	succeeded = true
+
+	go func() {
+		klog.Info("starting the embedded hub controller-runtime manager in controlplane agent")
+		if err := hubManager.Start(ctx); err != nil {
+			klog.Errorf("failed to start embedded hub controller-runtime manager, %v", err)
+		}
+	}()
+
+	go func() {
+		klog.Info("starting the embedded hosting controller-runtime manager in controlplane agent")
+		if err := hostingManager.Start(ctx); err != nil {
+			klog.Errorf("failed to start embedded hosting controller-runtime manager, %v", err)
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,63 @@
+// Copyright Contributors to the Open Cluster Management project
+package addon
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// setupBackoff is the backoff of retrying a failed addon setup, the retry interval is capped at 5 minutes.
+var setupBackoff = wait.Backoff{
+	Duration: 5 * time.Second,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    math.MaxInt32,
+	Cap:      5 * time.Minute,
+}
+
+// RetrySetup retries the setup of an addon with exponential backoff until it succeeds or the context is done, the
+// report func is called with the result of each attempt.
+func RetrySetup(ctx context.Context, setup func(ctx context.Context) error, report func(err error)) {
+	backoff := setupBackoff
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff.Step()):
+		}
+
+		err := setup(ctx)
+		report(err)
+		if err == nil {
+			return
+		}
+	}
+}
+
+// SetupStep is a step of the addon setup, e.g. registering a controller with the manager.
+type SetupStep func(ctx context.Context) error
+
+// SetupSteps runs the setup steps of an addon in order and records the succeeded steps, so a failed setup can be
+// retried without running the succeeded steps (e.g. registering their controllers) again.
+type SetupSteps struct {
+	lock sync.Mutex
+	done int
+}
+
+// Run runs the steps that have not succeeded yet, it stops at the first failed step. The steps must be passed in the
+// same order on every run.
+func (s *SetupSteps) Run(ctx context.Context, steps ...SetupStep) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for ; s.done < len(steps); s.done++ {
+		if err := steps[s.done](ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
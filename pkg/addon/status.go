@@ -0,0 +1,116 @@
+// Copyright Contributors to the Open Cluster Management project
+package addon
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// ConditionAvailable indicates whether the addon is started.
+	ConditionAvailable = "Available"
+
+	ReasonAddOnStarted     = "AddOnStarted"
+	ReasonAddOnSetupFailed = "AddOnSetupFailed"
+	ReasonAddOnDisabled    = "AddOnDisabled"
+)
+
+// AddOnStatusGVR is the resource of the cluster-scoped AddOnStatus, it records the startup status of an addon
+// in the controlplane.
+var AddOnStatusGVR = schema.GroupVersionResource{
+	Group:    "controlplane.open-cluster-management.io",
+	Version:  "v1alpha1",
+	Resource: "addonstatuses",
+}
+
+// NewSetupCondition returns the Available condition of an addon with the result of its setup.
+func NewSetupCondition(err error) metav1.Condition {
+	if err != nil {
+		return metav1.Condition{
+			Type:    ConditionAvailable,
+			Status:  metav1.ConditionFalse,
+			Reason:  ReasonAddOnSetupFailed,
+			Message: fmt.Sprintf("Failed to setup the addon and will retry, %v", err),
+		}
+	}
+
+	return metav1.Condition{
+		Type:    ConditionAvailable,
+		Status:  metav1.ConditionTrue,
+		Reason:  ReasonAddOnStarted,
+		Message: "The addon is started",
+	}
+}
+
+// AgentStatusName returns the name of the AddOnStatus of an addon agent on the cluster that the agent is running
+// on, the agents of the managed clusters may run on the same management cluster in the hosted mode.
+func AgentStatusName(clusterName, addOnName string) string {
+	return fmt.Sprintf("%s.%s", clusterName, addOnName)
+}
+
+// UpdateStatusCondition sets the condition on the AddOnStatus of the given addon, the AddOnStatus is created if
+// it does not exist.
+func UpdateStatusCondition(ctx context.Context, client dynamic.Interface, name string, cond metav1.Condition) error {
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		status, err := client.Resource(AddOnStatusGVR).Get(ctx, name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			status, err = client.Resource(AddOnStatusGVR).Create(ctx, &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"apiVersion": AddOnStatusGVR.GroupVersion().String(),
+					"kind":       "AddOnStatus",
+					"metadata": map[string]interface{}{
+						"name": name,
+					},
+				},
+			}, metav1.CreateOptions{})
+		}
+		if err != nil {
+			return err
+		}
+
+		conditions := []metav1.Condition{}
+		rawConditions, _, err := unstructured.NestedSlice(status.Object, "status", "conditions")
+		if err != nil {
+			return err
+		}
+		for _, rawCondition := range rawConditions {
+			condition := metav1.Condition{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(
+				rawCondition.(map[string]interface{}), &condition); err != nil {
+				return err
+			}
+			conditions = append(conditions, condition)
+		}
+
+		existing := meta.FindStatusCondition(conditions, cond.Type)
+		if existing != nil && existing.Status == cond.Status &&
+			existing.Reason == cond.Reason && existing.Message == cond.Message {
+			return nil
+		}
+		meta.SetStatusCondition(&conditions, cond)
+
+		rawConditions = []interface{}{}
+		for _, condition := range conditions {
+			rawCondition, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&condition)
+			if err != nil {
+				return err
+			}
+			rawConditions = append(rawConditions, rawCondition)
+		}
+		if err := unstructured.SetNestedSlice(status.Object, rawConditions, "status", "conditions"); err != nil {
+			return err
+		}
+
+		_, err = client.Resource(AddOnStatusGVR).UpdateStatus(ctx, status, metav1.UpdateOptions{})
+		return err
+	})
+}
@@ -0,0 +1,195 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	actionv1beta1 "github.com/stolostron/cluster-lifecycle-api/action/v1beta1"
+	actionclient "github.com/stolostron/cluster-lifecycle-api/client/action/clientset/versioned"
+	actioninformer "github.com/stolostron/cluster-lifecycle-api/client/action/informers/externalversions/action/v1beta1"
+	actionlister "github.com/stolostron/cluster-lifecycle-api/client/action/listers/action/v1beta1"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+)
+
+// actionController executes the ManagedClusterActions in the cluster namespace on the managed cluster, an action is
+// executed only once, the result of the action is recorded by the Completed condition of the action.
+type actionController struct {
+	clusterName        string
+	actionClient       actionclient.Interface
+	actionLister       actionlister.ManagedClusterActionLister
+	spokeDynamicClient dynamic.Interface
+	spokeRestMapper    meta.RESTMapper
+}
+
+func NewActionController(
+	clusterName string,
+	actionClient actionclient.Interface,
+	actionInformer actioninformer.ManagedClusterActionInformer,
+	spokeDynamicClient dynamic.Interface,
+	spokeRestMapper meta.RESTMapper,
+	recorder events.Recorder,
+) factory.Controller {
+	controller := &actionController{
+		clusterName:        clusterName,
+		actionClient:       actionClient,
+		actionLister:       actionInformer.Lister(),
+		spokeDynamicClient: spokeDynamicClient,
+		spokeRestMapper:    spokeRestMapper,
+	}
+
+	return factory.New().WithSync(controller.sync).
+		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			return accessor.GetName()
+		}, actionInformer.Informer()).
+		ToController("ManagedClusterActionController", recorder)
+}
+
+func (c *actionController) sync(ctx context.Context, controllerContext factory.SyncContext) error {
+	actionName := controllerContext.QueueKey()
+	if actionName == "" || actionName == factory.DefaultQueueKey {
+		return nil
+	}
+
+	action, err := c.actionLister.ManagedClusterActions(c.clusterName).Get(actionName)
+	switch {
+	case errors.IsNotFound(err):
+		return nil
+	case err != nil:
+		return err
+	}
+
+	if !action.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	// the action is already executed
+	if meta.FindStatusCondition(action.Status.Conditions, actionv1beta1.ConditionActionCompleted) != nil {
+		return nil
+	}
+
+	newAction := action.DeepCopy()
+	result, err := c.execute(ctx, action.Spec)
+	if err != nil {
+		klog.V(4).Infof("failed to execute action %s/%s, %v", c.clusterName, actionName, err)
+		meta.SetStatusCondition(&newAction.Status.Conditions, metav1.Condition{
+			Type:    actionv1beta1.ConditionActionCompleted,
+			Status:  metav1.ConditionFalse,
+			Reason:  failedReason(action.Spec.ActionType),
+			Message: err.Error(),
+		})
+	} else {
+		newAction.Status.Result = result
+		meta.SetStatusCondition(&newAction.Status.Conditions, metav1.Condition{
+			Type:    actionv1beta1.ConditionActionCompleted,
+			Status:  metav1.ConditionTrue,
+			Reason:  "ActionDone",
+			Message: "Resource action is done.",
+		})
+	}
+
+	_, err = c.actionClient.ActionV1beta1().ManagedClusterActions(c.clusterName).UpdateStatus(
+		ctx, newAction, metav1.UpdateOptions{})
+	return err
+}
+
+// execute creates, updates or deletes the resource of the action on the managed cluster, the created or updated
+// resource is returned.
+func (c *actionController) execute(ctx context.Context, spec actionv1beta1.ActionSpec) (runtime.RawExtension, error) {
+	if spec.KubeWork == nil {
+		return runtime.RawExtension{}, fmt.Errorf("the kube work of the action is required")
+	}
+
+	switch spec.ActionType {
+	case actionv1beta1.CreateActionType, actionv1beta1.UpdateActionType:
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(spec.KubeWork.ObjectTemplate.Raw); err != nil {
+			return runtime.RawExtension{}, fmt.Errorf("invalid template, %v", err)
+		}
+		if len(obj.GetNamespace()) == 0 {
+			obj.SetNamespace(spec.KubeWork.Namespace)
+		}
+
+		gvk := obj.GroupVersionKind()
+		mapping, err := c.spokeRestMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return runtime.RawExtension{}, err
+		}
+		resourceClient := c.resourceClient(mapping, obj.GetNamespace())
+
+		var result *unstructured.Unstructured
+		if spec.ActionType == actionv1beta1.CreateActionType {
+			result, err = resourceClient.Create(ctx, obj, metav1.CreateOptions{})
+		} else {
+			existing, getErr := resourceClient.Get(ctx, obj.GetName(), metav1.GetOptions{})
+			if getErr != nil {
+				return runtime.RawExtension{}, getErr
+			}
+			obj.SetResourceVersion(existing.GetResourceVersion())
+			result, err = resourceClient.Update(ctx, obj, metav1.UpdateOptions{})
+		}
+		if err != nil {
+			return runtime.RawExtension{}, err
+		}
+
+		raw, err := json.Marshal(result)
+		if err != nil {
+			return runtime.RawExtension{}, err
+		}
+		return runtime.RawExtension{Raw: raw}, nil
+	case actionv1beta1.DeleteActionType:
+		gvr, err := c.spokeRestMapper.ResourceFor(schema.ParseGroupResource(spec.KubeWork.Resource).WithVersion(""))
+		if err != nil {
+			return runtime.RawExtension{}, err
+		}
+		gvk, err := c.spokeRestMapper.KindFor(gvr)
+		if err != nil {
+			return runtime.RawExtension{}, err
+		}
+		mapping, err := c.spokeRestMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return runtime.RawExtension{}, err
+		}
+
+		err = c.resourceClient(mapping, spec.KubeWork.Namespace).Delete(
+			ctx, spec.KubeWork.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return runtime.RawExtension{}, err
+		}
+		return runtime.RawExtension{}, nil
+	default:
+		return runtime.RawExtension{}, fmt.Errorf("the action type %q is not supported", spec.ActionType)
+	}
+}
+
+func (c *actionController) resourceClient(mapping *meta.RESTMapping, namespace string) dynamic.ResourceInterface {
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		return c.spokeDynamicClient.Resource(mapping.Resource).Namespace(namespace)
+	}
+	return c.spokeDynamicClient.Resource(mapping.Resource)
+}
+
+func failedReason(actionType actionv1beta1.ActionType) string {
+	switch actionType {
+	case actionv1beta1.CreateActionType:
+		return actionv1beta1.ReasonCreateResourceFailed
+	case actionv1beta1.UpdateActionType:
+		return actionv1beta1.ReasonUpdateResourceFailed
+	case actionv1beta1.DeleteActionType:
+		return actionv1beta1.ReasonDeleteResourceFailed
+	default:
+		return actionv1beta1.ReasonActionTypeInvalid
+	}
+}
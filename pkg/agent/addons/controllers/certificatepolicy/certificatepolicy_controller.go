@@ -0,0 +1,467 @@
+// Copyright Contributors to the Open Cluster Management project
+package certificatepolicy
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"open-cluster-management.io/governance-policy-framework-addon/controllers/utils"
+	policyv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	ControllerName = "certificate-policy-controller"
+
+	// the certificates are re-evaluated periodically, because they become non-compliant as time goes by
+	evaluationInterval = 5 * time.Minute
+)
+
+var CertificatePolicyGVK = schema.GroupVersionKind{
+	Group:   "policy.open-cluster-management.io",
+	Version: "v1",
+	Kind:    "CertificatePolicy",
+}
+
+type target struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+type certificatePolicySpec struct {
+	NamespaceSelector    target            `json:"namespaceSelector,omitempty"`
+	LabelSelector        map[string]string `json:"labelSelector,omitempty"`
+	MinimumDuration      string            `json:"minimumDuration,omitempty"`
+	MinimumCADuration    string            `json:"minimumCADuration,omitempty"`
+	MaximumDuration      string            `json:"maximumDuration,omitempty"`
+	MaximumCADuration    string            `json:"maximumCADuration,omitempty"`
+	AllowedSANPattern    string            `json:"allowedSANPattern,omitempty"`
+	DisallowedSANPattern string            `json:"disallowedSANPattern,omitempty"`
+}
+
+type certificatePolicyStatus struct {
+	ComplianceState   policyv1.ComplianceState     `json:"compliant,omitempty"`
+	CompliancyDetails map[string]compliancyDetails `json:"compliancyDetails,omitempty"`
+}
+
+type compliancyDetails struct {
+	Message                      string          `json:"message,omitempty"`
+	NonCompliantCertificates     int64           `json:"nonCompliantCertificates,omitempty"`
+	NonCompliantCertificatesList map[string]cert `json:"nonCompliantCertificatesList,omitempty"`
+}
+
+type cert struct {
+	SecretName string   `json:"secretName,omitempty"`
+	Expiration string   `json:"expiration,omitempty"`
+	Expiry     int64    `json:"expiry,omitempty"`
+	CA         bool     `json:"ca,omitempty"`
+	Duration   int64    `json:"duration,omitempty"`
+	Sans       []string `json:"sans,omitempty"`
+}
+
+// rules are the parsed certificate rules of a certificate policy
+type rules struct {
+	minimumDuration      *time.Duration
+	minimumCADuration    *time.Duration
+	maximumDuration      *time.Duration
+	maximumCADuration    *time.Duration
+	allowedSANPattern    *regexp.Regexp
+	disallowedSANPattern *regexp.Regexp
+}
+
+// CertificatePolicyReconciler evaluates the certificate policies in the cluster namespace against the TLS secrets
+// of the managed cluster, the compliance of a certificate policy is reported to its parent policy by the compliance
+// events, which are synced to the hub by the policy status sync controller.
+type CertificatePolicyReconciler struct {
+	client.Client
+	utils.ComplianceEventSender
+	// TargetK8sClient is the client of the managed cluster that the TLS secrets are checked on
+	TargetK8sClient kubernetes.Interface
+	// lastSentCompliance records the compliance events that are sent for the certificate policies, a compliance
+	// event is sent again if it is failed to be sent after the status is updated.
+	lastSentCompliance sync.Map
+}
+
+// complianceEvent is the compliance and the message of a compliance event
+type complianceEvent struct {
+	compliance policyv1.ComplianceState
+	message    string
+}
+
+// SetupWithManager sets up the controller with the hosting manager.
+func (r *CertificatePolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	certificatePolicy := &unstructured.Unstructured{}
+	certificatePolicy.SetGroupVersionKind(CertificatePolicyGVK)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(certificatePolicy).
+		Named(ControllerName).
+		Complete(r)
+}
+
+func (r *CertificatePolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	certificatePolicy := &unstructured.Unstructured{}
+	certificatePolicy.SetGroupVersionKind(CertificatePolicyGVK)
+	if err := r.Get(ctx, req.NamespacedName, certificatePolicy); err != nil {
+		if errors.IsNotFound(err) {
+			r.lastSentCompliance.Delete(req.NamespacedName)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	if !certificatePolicy.GetDeletionTimestamp().IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	status, err := r.evaluate(ctx, certificatePolicy)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	lastStatus := certificatePolicyStatus{}
+	if lastStatusObj, ok := certificatePolicy.Object["status"].(map[string]interface{}); ok {
+		// an invalid status is overwritten by the new status
+		_ = runtime.DefaultUnstructuredConverter.FromUnstructured(lastStatusObj, &lastStatus)
+	}
+
+	if !reflect.DeepEqual(stripExpiry(lastStatus), stripExpiry(*status)) {
+		statusObj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(status)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		certificatePolicy.Object["status"] = statusObj
+		if err := r.Status().Update(ctx, certificatePolicy); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	event := newComplianceEvent(status)
+	if lastSent, ok := r.lastSentCompliance.Load(req.NamespacedName); !ok || lastSent.(complianceEvent) != event {
+		if err := r.sendComplianceEvent(ctx, certificatePolicy, event); err != nil {
+			return ctrl.Result{}, err
+		}
+		r.lastSentCompliance.Store(req.NamespacedName, event)
+	}
+
+	return ctrl.Result{RequeueAfter: evaluationInterval}, nil
+}
+
+func (r *CertificatePolicyReconciler) evaluate(
+	ctx context.Context, certificatePolicy *unstructured.Unstructured) (*certificatePolicyStatus, error) {
+	spec := certificatePolicySpec{}
+	if specObj, ok := certificatePolicy.Object["spec"].(map[string]interface{}); ok {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(specObj, &spec); err != nil {
+			return invalidStatus(fmt.Errorf("invalid spec, %v", err)), nil
+		}
+	}
+
+	rules, err := parseRules(spec)
+	if err != nil {
+		return invalidStatus(err), nil
+	}
+
+	namespaces, err := r.selectNamespaces(ctx, spec.NamespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &certificatePolicyStatus{
+		ComplianceState:   policyv1.Compliant,
+		CompliancyDetails: map[string]compliancyDetails{},
+	}
+	for _, namespace := range namespaces {
+		secrets, err := r.TargetK8sClient.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: labels.SelectorFromSet(spec.LabelSelector).String(),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		nonCompliantCerts := map[string]cert{}
+		for _, secret := range secrets.Items {
+			certificate, ok := parseCertificate(secret)
+			if !ok {
+				continue
+			}
+			if c, compliant := rules.check(secret.Name, certificate); !compliant {
+				nonCompliantCerts[secret.Name] = c
+			}
+		}
+
+		details := compliancyDetails{NonCompliantCertificates: int64(len(nonCompliantCerts))}
+		if len(nonCompliantCerts) == 0 {
+			details.Message = fmt.Sprintf("Found 0 non compliant certificates in the namespace %s", namespace)
+		} else {
+			status.ComplianceState = policyv1.NonCompliant
+			details.NonCompliantCertificatesList = nonCompliantCerts
+			details.Message = fmt.Sprintf(
+				"Found %d non compliant certificates in the namespace %s, list of non compliant certificates: %s",
+				len(nonCompliantCerts), namespace, strings.Join(sortedKeys(nonCompliantCerts), ", "))
+		}
+		status.CompliancyDetails[namespace] = details
+	}
+
+	return status, nil
+}
+
+// selectNamespaces returns the namespaces of the managed cluster that match the include filepath expressions and do
+// not match the exclude filepath expressions, all namespaces are included if there are no include expressions.
+func (r *CertificatePolicyReconciler) selectNamespaces(ctx context.Context, selector target) ([]string, error) {
+	namespaces, err := r.TargetK8sClient.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	selected := []string{}
+	for _, namespace := range namespaces.Items {
+		if len(selector.Include) > 0 && !matchAny(selector.Include, namespace.Name) {
+			continue
+		}
+		if matchAny(selector.Exclude, namespace.Name) {
+			continue
+		}
+		selected = append(selected, namespace.Name)
+	}
+	sort.Strings(selected)
+	return selected, nil
+}
+
+// newComplianceEvent returns the compliance event of the status, the message joins the messages of the namespaces.
+func newComplianceEvent(status *certificatePolicyStatus) complianceEvent {
+	messages := []string{}
+	for _, namespace := range sortedKeys(status.CompliancyDetails) {
+		messages = append(messages, status.CompliancyDetails[namespace].Message)
+	}
+	if len(messages) == 0 {
+		messages = append(messages, "No namespaces are selected")
+	}
+
+	return complianceEvent{
+		compliance: status.ComplianceState,
+		message:    strings.Join(messages, "; "),
+	}
+}
+
+func (r *CertificatePolicyReconciler) sendComplianceEvent(
+	ctx context.Context, certificatePolicy *unstructured.Unstructured, event complianceEvent) error {
+	var owner *metav1.OwnerReference
+	for _, ownerRef := range certificatePolicy.GetOwnerReferences() {
+		if ownerRef.Kind == "Policy" {
+			ownerRef := ownerRef
+			owner = &ownerRef
+			break
+		}
+	}
+	if owner == nil {
+		// the certificate policy is not created from a policy, there is no policy to report the compliance to
+		klog.V(4).Infof("certificate policy %s/%s has no parent policy",
+			certificatePolicy.GetNamespace(), certificatePolicy.GetName())
+		return nil
+	}
+
+	return r.SendEvent(
+		ctx,
+		certificatePolicy,
+		*owner,
+		utils.EventReason(certificatePolicy.GetNamespace(), certificatePolicy.GetName()),
+		event.message,
+		event.compliance,
+	)
+}
+
+func parseRules(spec certificatePolicySpec) (*rules, error) {
+	r := &rules{}
+
+	durations := []struct {
+		name  string
+		value string
+		into  **time.Duration
+	}{
+		{"minimumDuration", spec.MinimumDuration, &r.minimumDuration},
+		{"minimumCADuration", spec.MinimumCADuration, &r.minimumCADuration},
+		{"maximumDuration", spec.MaximumDuration, &r.maximumDuration},
+		{"maximumCADuration", spec.MaximumCADuration, &r.maximumCADuration},
+	}
+	for _, d := range durations {
+		if len(d.value) == 0 {
+			continue
+		}
+		duration, err := time.ParseDuration(d.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q, %v", d.name, d.value, err)
+		}
+		*d.into = &duration
+	}
+
+	patterns := []struct {
+		name  string
+		value string
+		into  **regexp.Regexp
+	}{
+		{"allowedSANPattern", spec.AllowedSANPattern, &r.allowedSANPattern},
+		{"disallowedSANPattern", spec.DisallowedSANPattern, &r.disallowedSANPattern},
+	}
+	for _, p := range patterns {
+		if len(p.value) == 0 {
+			continue
+		}
+		pattern, err := regexp.Compile(p.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q, %v", p.name, p.value, err)
+		}
+		*p.into = pattern
+	}
+
+	return r, nil
+}
+
+// check returns the details of the certificate and whether the certificate is compliant with the rules, the CA
+// durations are used for the CA certificates if they are set, otherwise the durations are used for all certificates.
+func (r *rules) check(secretName string, certificate *x509.Certificate) (cert, bool) {
+	expiry := time.Until(certificate.NotAfter)
+	duration := certificate.NotAfter.Sub(certificate.NotBefore)
+	sans := certificateSANs(certificate)
+
+	c := cert{
+		SecretName: secretName,
+		Expiration: certificate.NotAfter.UTC().Format(time.RFC3339),
+		Expiry:     int64(expiry),
+		CA:         certificate.IsCA,
+		Duration:   int64(duration),
+		Sans:       sans,
+	}
+
+	minimum, maximum := r.minimumDuration, r.maximumDuration
+	if certificate.IsCA {
+		if r.minimumCADuration != nil {
+			minimum = r.minimumCADuration
+		}
+		if r.maximumCADuration != nil {
+			maximum = r.maximumCADuration
+		}
+	}
+
+	if minimum != nil && expiry < *minimum {
+		return c, false
+	}
+	if maximum != nil && duration > *maximum {
+		return c, false
+	}
+
+	for _, san := range sans {
+		if r.allowedSANPattern != nil && !r.allowedSANPattern.MatchString(san) {
+			return c, false
+		}
+		if r.disallowedSANPattern != nil && r.disallowedSANPattern.MatchString(san) {
+			return c, false
+		}
+	}
+
+	return c, true
+}
+
+// parseCertificate returns the first certificate of the tls.crt in the secret.
+func parseCertificate(secret corev1.Secret) (*x509.Certificate, bool) {
+	data, ok := secret.Data[corev1.TLSCertKey]
+	if !ok {
+		return nil, false
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		klog.V(4).Infof("the %s of secret %s/%s is not PEM encoded", corev1.TLSCertKey, secret.Namespace, secret.Name)
+		return nil, false
+	}
+
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		klog.V(4).Infof("failed to parse the certificate of secret %s/%s, %v", secret.Namespace, secret.Name, err)
+		return nil, false
+	}
+
+	return certificate, true
+}
+
+func certificateSANs(certificate *x509.Certificate) []string {
+	sans := []string{}
+	sans = append(sans, certificate.DNSNames...)
+	for _, ip := range certificate.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, certificate.EmailAddresses...)
+	for _, uri := range certificate.URIs {
+		sans = append(sans, uri.String())
+	}
+	return sans
+}
+
+func invalidStatus(err error) *certificatePolicyStatus {
+	return &certificatePolicyStatus{
+		ComplianceState: policyv1.NonCompliant,
+		CompliancyDetails: map[string]compliancyDetails{
+			"": {Message: fmt.Sprintf("the certificate policy is invalid, %v", err)},
+		},
+	}
+}
+
+// stripExpiry removes the expiry of the non-compliant certificates, which changes on every evaluation, and the empty
+// fields to compare the status.
+func stripExpiry(status certificatePolicyStatus) certificatePolicyStatus {
+	stripped := certificatePolicyStatus{
+		ComplianceState:   status.ComplianceState,
+		CompliancyDetails: map[string]compliancyDetails{},
+	}
+	for namespace, details := range status.CompliancyDetails {
+		certs := map[string]cert{}
+		for name, c := range details.NonCompliantCertificatesList {
+			c.Expiry = 0
+			if len(c.Sans) == 0 {
+				c.Sans = nil
+			}
+			certs[name] = c
+		}
+		details.NonCompliantCertificatesList = certs
+		stripped.CompliancyDetails[namespace] = details
+	}
+	return stripped
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
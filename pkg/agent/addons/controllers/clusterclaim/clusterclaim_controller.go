@@ -10,6 +10,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
 	clusterclientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
 	clusterv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -23,6 +24,9 @@ type ListClusterClaimsFunc func() ([]*clusterv1alpha1.ClusterClaim, error)
 type ClusterClaimReconciler struct {
 	ListClusterClaims ListClusterClaimsFunc
 	ClusterClient     clusterclientset.Interface
+	// ClaimProviders provide the custom claims, the claims of the ListClusterClaims take precedence over the custom
+	// claims with the same names.
+	ClaimProviders []ClaimProvider
 }
 
 func (r *ClusterClaimReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -46,6 +50,28 @@ func (r *ClusterClaimReconciler) syncClaims(ctx context.Context) error {
 		claimSet.Insert(claim.Name)
 	}
 
+	// the existing claims of the failed providers are kept
+	failedProviders := sets.Set[string]{}
+	for _, provider := range r.ClaimProviders {
+		customClaims, createOnly, err := provider.Claims(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get the claims of the provider %q: %w", provider.Name(), err))
+			failedProviders.Insert(provider.Name())
+		}
+
+		for _, claim := range customClaims {
+			if claimSet.Has(claim.Name) {
+				klog.Warningf("skip the claim %q of the provider %q, the claim already exists", claim.Name, provider.Name())
+				continue
+			}
+
+			if err := r.createOrUpdate(ctx, claim, createOnly); err != nil {
+				errs = append(errs, err)
+			}
+			claimSet.Insert(claim.Name)
+		}
+	}
+
 	labelSelector := fmt.Sprintf("%s=%s", labelHubManaged, "")
 	existedObjs, err := r.ClusterClient.ClusterV1alpha1().ClusterClaims().List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
 	if err != nil {
@@ -63,6 +89,11 @@ func (r *ClusterClaimReconciler) syncClaims(ctx context.Context) error {
 		if claimSet.Has(claim.Name) {
 			continue
 		}
+
+		if provider, ok := claim.Labels[labelClaimProvider]; ok && failedProviders.Has(provider) {
+			continue
+		}
+
 		err := r.ClusterClient.ClusterV1alpha1().ClusterClaims().Delete(ctx, claim.Name, metav1.DeleteOptions{})
 		if err != nil {
 			errs = append(errs, err)
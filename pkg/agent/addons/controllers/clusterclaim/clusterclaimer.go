@@ -19,9 +19,11 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes"
+	corev1lister "k8s.io/client-go/listers/core/v1"
 	"k8s.io/klog/v2"
 	clusterv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -42,6 +44,9 @@ const (
 	ClaimOCMKubeVersion = "kubeversion.open-cluster-management.io"
 	ClaimOCMPlatform    = "platform.open-cluster-management.io"
 	ClaimOCMProduct     = "product.open-cluster-management.io"
+	// ClaimOCMKubeDistribution is the kubernetes distribution that is inferred from the node labels and the gitVersion
+	// of the kube-apiserver, e.g. EKS, AKS, GKE, K3s, RKE2 and Vanilla.
+	ClaimOCMKubeDistribution = "kubedistribution.open-cluster-management.io"
 )
 
 const (
@@ -99,6 +104,14 @@ var ProductOCPList = []string{
 	ProductROKS,
 }
 
+// the labels that are auto detected with the claims and the nodes of the managed cluster
+const (
+	LabelRegion                = "region"
+	LabelKubeVersionMajorMinor = "kubeVersion-major-minor"
+	LabelArchitecture          = "architecture"
+	LabelControlPlaneTopology  = "controlPlaneTopology"
+)
+
 // internalLabels includes the labels managed by ACM.
 var internalLabels = sets.Set[string]{}
 
@@ -107,7 +120,11 @@ func init() {
 		clusterv1beta1.LabelCloudVendor,
 		clusterv1beta1.LabelKubeVendor,
 		clusterv1beta1.LabelManagedBy,
-		clusterv1beta1.OCPVersion)
+		clusterv1beta1.OCPVersion,
+		LabelRegion,
+		LabelKubeVersionMajorMinor,
+		LabelArchitecture,
+		LabelControlPlaneTopology)
 }
 
 type ClusterClaimer struct {
@@ -117,6 +134,7 @@ type ClusterClaimer struct {
 	HubClient                       client.Client
 	ManagedClusterInfoList          clusterv1beta1infolister.ManagedClusterInfoLister
 	KubeClient                      kubernetes.Interface
+	NodeLister                      corev1lister.NodeLister
 	ConfigV1Client                  openshiftclientset.Interface
 	OauthV1Client                   openshiftoauthclientset.Interface
 	Mapper                          meta.RESTMapper
@@ -220,6 +238,15 @@ func (c *ClusterClaimer) List() ([]*clusterv1alpha1.ClusterClaim, error) {
 		return claims, err
 	}
 	claims = append(claims, newClusterClaim(ClaimOCMKubeVersion, kubeVersion))
+	if isOpenShift, _ := c.isOpenShift(); !isOpenShift {
+		nodes, err := c.NodeLister.List(labels.Everything())
+		if err != nil {
+			klog.Errorf("failed to list nodes: %v", err)
+			return claims, err
+		}
+		distributionInfo := NewKubernetesDistributionInfo(kubeVersion, nodes)
+		claims = append(claims, newClusterClaim(ClaimOCMKubeDistribution, distributionInfo.Distribution))
+	}
 
 	region, err := c.getClusterRegion()
 	if err != nil {
@@ -0,0 +1,255 @@
+package clusterclaim
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	corev1lister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/util/jsonpath"
+	clusterv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// CustomClaimsConfigMapNamespace and CustomClaimsConfigMapName are the namespace and name of the ConfigMap that
+	// defines the custom claims on the managed cluster.
+	CustomClaimsConfigMapNamespace = "open-cluster-management-agent-addon"
+	CustomClaimsConfigMapName      = "custom-cluster-claims"
+
+	// customClaimsConfigMapKey is the key of the custom claims definition in the ConfigMap
+	customClaimsConfigMapKey = "claims.yaml"
+
+	configMapClaimProviderName = "configmap"
+
+	// the max length of the claim value, see the ClusterClaim API
+	maxClaimValueLength = 1024
+)
+
+// allowedJSONPathGroupKinds are the resources that can be evaluated by the JSONPath claims, the claims are visible on
+// the controlplane and the resources are read with the permissions of the agent, so only the resources that do not
+// have sensitive data are allowed.
+var allowedJSONPathGroupKinds = sets.New[schema.GroupKind](
+	schema.GroupKind{Group: "", Kind: "ConfigMap"},
+	schema.GroupKind{Group: "", Kind: "Namespace"},
+	schema.GroupKind{Group: "", Kind: "Node"},
+	schema.GroupKind{Group: "config.openshift.io", Kind: "ClusterVersion"},
+	schema.GroupKind{Group: "config.openshift.io", Kind: "Infrastructure"},
+	schema.GroupKind{Group: "config.openshift.io", Kind: "Network"},
+	schema.GroupKind{Group: "config.openshift.io", Kind: "DNS"},
+)
+
+// the aggregations of the node label values
+const (
+	// NodeLabelAggregationUnique joins the sorted unique values of the label with comma
+	NodeLabelAggregationUnique = "Unique"
+	// NodeLabelAggregationCount is the number of the nodes that have the label
+	NodeLabelAggregationCount = "Count"
+)
+
+// CustomClaims is the definition of the custom claims in the ConfigMap, e.g.
+//
+//	claims:
+//	- name: datacenter.example.com
+//	  value: dc1
+//	- name: tier.compliance.example.com
+//	  createOnly: true
+//	  jsonPath:
+//	    apiVersion: v1
+//	    kind: ConfigMap
+//	    namespace: kube-system
+//	    name: compliance
+//	    path: "{.data.tier}"
+//	- name: model.gpu.example.com
+//	  nodeLabel:
+//	    key: nvidia.com/gpu.product
+//	    aggregation: Unique
+type CustomClaims struct {
+	Claims []CustomClaim `json:"claims"`
+}
+
+// CustomClaim defines a claim, the value of the claim is from one of the Value, JSONPath and NodeLabel.
+type CustomClaim struct {
+	Name string `json:"name"`
+	// CreateOnly claims are not updated once they are created.
+	CreateOnly bool                 `json:"createOnly,omitempty"`
+	Value      string               `json:"value,omitempty"`
+	JSONPath   *JSONPathClaimValue  `json:"jsonPath,omitempty"`
+	NodeLabel  *NodeLabelClaimValue `json:"nodeLabel,omitempty"`
+}
+
+// JSONPathClaimValue is the value that is evaluated with the JSONPath template on a resource of the managed cluster,
+// only the resources of the allowedJSONPathGroupKinds are allowed.
+type JSONPathClaimValue struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	Path       string `json:"path"`
+}
+
+// NodeLabelClaimValue is the value that is aggregated with a label of the nodes of the managed cluster.
+type NodeLabelClaimValue struct {
+	Key string `json:"key"`
+	// Aggregation is Unique or Count, Unique by default.
+	Aggregation string `json:"aggregation,omitempty"`
+}
+
+// ConfigMapClaimProvider provides the custom claims that are defined in a ConfigMap on the managed cluster.
+type ConfigMapClaimProvider struct {
+	KubeClient    kubernetes.Interface
+	DynamicClient dynamic.Interface
+	Mapper        meta.RESTMapper
+	NodeLister    corev1lister.NodeLister
+	// ConfigMapNamespace and ConfigMapName are the namespace and name of the ConfigMap that defines the claims.
+	ConfigMapNamespace string
+	ConfigMapName      string
+}
+
+var _ ClaimProvider = &ConfigMapClaimProvider{}
+
+func (p *ConfigMapClaimProvider) Name() string {
+	return configMapClaimProviderName
+}
+
+func (p *ConfigMapClaimProvider) Claims(ctx context.Context) ([]*clusterv1alpha1.ClusterClaim, []string, error) {
+	configMap, err := p.KubeClient.CoreV1().ConfigMaps(p.ConfigMapNamespace).Get(ctx, p.ConfigMapName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	customClaims := &CustomClaims{}
+	if err := yaml.Unmarshal([]byte(configMap.Data[customClaimsConfigMapKey]), customClaims); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse the custom claims in the configmap %s/%s: %v",
+			p.ConfigMapNamespace, p.ConfigMapName, err)
+	}
+
+	claims := []*clusterv1alpha1.ClusterClaim{}
+	createOnly := []string{}
+	errs := []error{}
+	for _, customClaim := range customClaims.Claims {
+		if msgs := validation.IsDNS1123Subdomain(customClaim.Name); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("the claim name %q is invalid: %s", customClaim.Name, strings.Join(msgs, ",")))
+			continue
+		}
+
+		value, err := p.claimValue(ctx, customClaim)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get the value of the claim %q: %v", customClaim.Name, err))
+			continue
+		}
+
+		// the value of ClusterClaim can not be empty
+		if len(value) == 0 {
+			continue
+		}
+		if len(value) > maxClaimValueLength {
+			errs = append(errs, fmt.Errorf("the value of the claim %q is longer than %d", customClaim.Name, maxClaimValueLength))
+			continue
+		}
+
+		claims = append(claims, newProviderClaim(p.Name(), customClaim.Name, value))
+		if customClaim.CreateOnly {
+			createOnly = append(createOnly, customClaim.Name)
+		}
+	}
+
+	return claims, createOnly, utilerrors.NewAggregate(errs)
+}
+
+func (p *ConfigMapClaimProvider) claimValue(ctx context.Context, customClaim CustomClaim) (string, error) {
+	switch {
+	case customClaim.JSONPath != nil:
+		return p.jsonPathValue(ctx, customClaim.JSONPath)
+	case customClaim.NodeLabel != nil:
+		return p.nodeLabelValue(customClaim.NodeLabel)
+	}
+	return customClaim.Value, nil
+}
+
+func (p *ConfigMapClaimProvider) jsonPathValue(ctx context.Context, jsonPathValue *JSONPathClaimValue) (string, error) {
+	gv, err := schema.ParseGroupVersion(jsonPathValue.APIVersion)
+	if err != nil {
+		return "", err
+	}
+
+	mapping, err := p.Mapper.RESTMapping(gv.WithKind(jsonPathValue.Kind).GroupKind(), gv.Version)
+	if err != nil {
+		return "", err
+	}
+
+	// the claims are visible on the controlplane, so the sensitive data must not be claimed
+	if !allowedJSONPathGroupKinds.Has(mapping.GroupVersionKind.GroupKind()) {
+		return "", fmt.Errorf("the %s can not be claimed", mapping.GroupVersionKind.GroupKind())
+	}
+
+	var resource dynamic.ResourceInterface = p.DynamicClient.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		resource = p.DynamicClient.Resource(mapping.Resource).Namespace(jsonPathValue.Namespace)
+	}
+
+	obj, err := resource.Get(ctx, jsonPathValue.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	path := jsonpath.New("claim").AllowMissingKeys(true)
+	if err := path.Parse(jsonPathValue.Path); err != nil {
+		return "", err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := path.Execute(buf, obj.Object); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+func (p *ConfigMapClaimProvider) nodeLabelValue(nodeLabelValue *NodeLabelClaimValue) (string, error) {
+	requirement, err := labels.NewRequirement(nodeLabelValue.Key, selection.Exists, nil)
+	if err != nil {
+		return "", err
+	}
+
+	nodes, err := p.NodeLister.List(labels.NewSelector().Add(*requirement))
+	if err != nil {
+		return "", err
+	}
+
+	switch nodeLabelValue.Aggregation {
+	case NodeLabelAggregationCount:
+		return strconv.Itoa(len(nodes)), nil
+	case NodeLabelAggregationUnique, "":
+		values := sets.Set[string]{}
+		for _, node := range nodes {
+			if value := node.Labels[nodeLabelValue.Key]; value != "" {
+				values.Insert(value)
+			}
+		}
+		sortedValues := values.UnsortedList()
+		sort.Strings(sortedValues)
+		return strings.Join(sortedValues, ","), nil
+	}
+
+	return "", fmt.Errorf("the node label aggregation %q is not supported", nodeLabelValue.Aggregation)
+}
@@ -0,0 +1,141 @@
+package clusterclaim
+
+import (
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+// the kubernetes distributions that are inferred from the node labels and the gitVersion of the kube-apiserver
+const (
+	DistributionEKS     = ProductEKS
+	DistributionAKS     = ProductAKS
+	DistributionGKE     = ProductGKE
+	DistributionK3s     = "K3s"
+	DistributionRKE2    = "RKE2"
+	DistributionVanilla = "Vanilla"
+)
+
+// the node labels that are set by the kubernetes distributions
+const (
+	labelEKSNodeGroup     = "eks.amazonaws.com/nodegroup"
+	labelGKENodePool      = "cloud.google.com/gke-nodepool"
+	labelAKSCluster       = "kubernetes.azure.com/cluster"
+	labelNodeInstanceType = "node.kubernetes.io/instance-type"
+)
+
+// KubernetesDistributionInfo is the distribution information of a kubernetes cluster.
+type KubernetesDistributionInfo struct {
+	// Distribution is the inferred kubernetes distribution, e.g. EKS, K3s or Vanilla.
+	Distribution string
+	// ServerVersion is the gitVersion of the kube-apiserver.
+	ServerVersion string
+	// Nodes are the versions of the nodes, sorted by the node name.
+	Nodes []NodeVersionInfo
+}
+
+// NodeVersionInfo is the kubelet and container runtime versions of a node.
+type NodeVersionInfo struct {
+	Name                    string
+	KubeletVersion          string
+	ContainerRuntimeVersion string
+	// Skewed is true if the kubelet version is not supported by the kube-apiserver, see
+	// https://kubernetes.io/releases/version-skew-policy/#kubelet
+	Skewed bool
+}
+
+// SkewedNodes returns the nodes whose kubelet version is not supported by the kube-apiserver.
+func (d *KubernetesDistributionInfo) SkewedNodes() []NodeVersionInfo {
+	nodes := []NodeVersionInfo{}
+	for _, node := range d.Nodes {
+		if node.Skewed {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// NewKubernetesDistributionInfo returns the distribution information of the cluster with the gitVersion of the
+// kube-apiserver and the nodes of the cluster.
+func NewKubernetesDistributionInfo(serverVersion string, nodes []*corev1.Node) *KubernetesDistributionInfo {
+	info := &KubernetesDistributionInfo{
+		Distribution:  inferDistribution(serverVersion, nodes),
+		ServerVersion: serverVersion,
+		Nodes:         []NodeVersionInfo{},
+	}
+
+	apiserverVersion, err := version.ParseGeneric(serverVersion)
+	for _, node := range nodes {
+		nodeVersion := NodeVersionInfo{
+			Name:                    node.Name,
+			KubeletVersion:          node.Status.NodeInfo.KubeletVersion,
+			ContainerRuntimeVersion: node.Status.NodeInfo.ContainerRuntimeVersion,
+		}
+		if err == nil {
+			nodeVersion.Skewed = isKubeletVersionSkewed(apiserverVersion, nodeVersion.KubeletVersion)
+		}
+		info.Nodes = append(info.Nodes, nodeVersion)
+	}
+
+	sort.Slice(info.Nodes, func(i, j int) bool { return info.Nodes[i].Name < info.Nodes[j].Name })
+	return info
+}
+
+// inferDistribution infers the distribution with the gitVersion suffixes firstly, e.g. v1.27.3+k3s1, v1.26.4-eks-0a21954
+// and v1.26.5-gke.1200, then with the node labels, the AKS is only inferred with the node labels.
+func inferDistribution(serverVersion string, nodes []*corev1.Node) string {
+	gitVersion := strings.ToLower(serverVersion)
+	switch {
+	case strings.Contains(gitVersion, "+k3s"):
+		return DistributionK3s
+	case strings.Contains(gitVersion, "+rke2"):
+		return DistributionRKE2
+	case strings.Contains(gitVersion, "-eks-"):
+		return DistributionEKS
+	case strings.Contains(gitVersion, "-gke."):
+		return DistributionGKE
+	}
+
+	for _, node := range nodes {
+		if _, ok := node.Labels[labelEKSNodeGroup]; ok {
+			return DistributionEKS
+		}
+		if _, ok := node.Labels[labelGKENodePool]; ok {
+			return DistributionGKE
+		}
+		if _, ok := node.Labels[labelAKSCluster]; ok {
+			return DistributionAKS
+		}
+		switch node.Labels[labelNodeInstanceType] {
+		case "k3s":
+			return DistributionK3s
+		case "rke2":
+			return DistributionRKE2
+		}
+	}
+
+	return DistributionVanilla
+}
+
+// isKubeletVersionSkewed returns true if the kubelet is newer than the kube-apiserver, or it is older than the
+// kube-apiserver by more than three minor versions (two minor versions before the kubernetes 1.28).
+func isKubeletVersionSkewed(apiserverVersion *version.Version, kubeletVersion string) bool {
+	kubelet, err := version.ParseGeneric(kubeletVersion)
+	if err != nil {
+		return false
+	}
+
+	if kubelet.Major() != apiserverVersion.Major() {
+		return true
+	}
+
+	maxSkew := 3
+	if apiserverVersion.Minor() < 28 {
+		maxSkew = 2
+	}
+
+	skew := int(apiserverVersion.Minor()) - int(kubelet.Minor())
+	return skew < 0 || skew > maxSkew
+}
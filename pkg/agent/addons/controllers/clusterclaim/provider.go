@@ -0,0 +1,29 @@
+package clusterclaim
+
+import (
+	"context"
+
+	clusterv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
+)
+
+// labelClaimProvider is the name of the provider that provides the claim.
+const labelClaimProvider = "open-cluster-management.io/claim-provider"
+
+// ClaimProvider provides the custom claims of the managed cluster, the custom claims are synced with the claims of
+// the ClusterClaimer, they are hub-managed and they are deleted once they are not provided.
+type ClaimProvider interface {
+	// Name is the name of the provider, it should be a valid label value.
+	Name() string
+
+	// Claims returns the custom claims, the claims in the createOnly are not updated once they are created. If the
+	// provider fails to compute some claims, the returned claims are synced, and the existing claims of the provider
+	// are not deleted.
+	Claims(ctx context.Context) (claims []*clusterv1alpha1.ClusterClaim, createOnly []string, err error)
+}
+
+// newProviderClaim returns a hub-managed claim that is provided by the provider.
+func newProviderClaim(provider, name, value string) *clusterv1alpha1.ClusterClaim {
+	claim := newClusterClaim(name, value)
+	claim.Labels[labelClaimProvider] = provider
+	return claim
+}
@@ -14,12 +14,14 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/client-go/kubernetes"
+	corev1lister "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
 	clusterv1alpha1lister "open-cluster-management.io/api/client/cluster/listers/cluster/v1alpha1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/stolostron/multicluster-controlplane/pkg/agent/metrics"
 	"github.com/stolostron/multicluster-controlplane/pkg/helpers"
 )
 
@@ -29,6 +31,7 @@ type ClusterInfoReconciler struct {
 	ManagedClusterInfoClient clusterinfoclient.Interface
 	ManagedClusterClient     kubernetes.Interface
 	ClaimLister              clusterv1alpha1lister.ClusterClaimLister
+	NodeLister               corev1lister.NodeLister
 	ManagedClusterInfoList   clusterv1beta1infolister.ManagedClusterInfoLister
 	ConfigV1Client           openshiftclientset.Interface
 	ClusterName              string
@@ -58,6 +61,10 @@ func (r *ClusterInfoReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			configV1Client:       r.ConfigV1Client,
 			managedClusterClient: r.ManagedClusterClient,
 			claimLister:          r.ClaimLister,
+			nodeLister:           r.NodeLister,
+		},
+		&nodeListSyncer{
+			nodeLister: r.NodeLister,
 		},
 	}
 
@@ -84,6 +91,7 @@ func (r *ClusterInfoReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	}
 	meta.SetStatusCondition(&newClusterInfo.Status.Conditions, newSyncedCondition)
 
+	synced := false
 	if err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
 		clusterInfo, err := r.ManagedClusterInfoClient.InternalV1beta1().ManagedClusterInfos(r.ClusterName).Get(ctx, r.ClusterName, metav1.GetOptions{})
 		if err != nil {
@@ -91,18 +99,24 @@ func (r *ClusterInfoReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		}
 
 		if !clusterInfoStatusUpdated(&clusterInfo.Status, &newClusterInfo.Status) {
+			synced = true
 			return nil
 		}
 
 		clusterInfo.Status = newClusterInfo.Status
 
 		_, err = r.ManagedClusterInfoClient.InternalV1beta1().ManagedClusterInfos(r.ClusterName).UpdateStatus(ctx, clusterInfo, metav1.UpdateOptions{})
+		synced = err == nil
 		return err
 	}); err != nil {
 		klog.Errorf("Failed to update clusterInfo status. error %v", err)
 		return ctrl.Result{}, err
 	}
 
+	if synced && len(errs) == 0 {
+		metrics.RecordClusterInfoSynced()
+	}
+
 	// need to sync ocp ClusterVersion info every 5 min since do not watch it.
 	return ctrl.Result{RequeueAfter: time.Minute * 5}, nil
 }
@@ -3,13 +3,18 @@ package clusterinfo
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 
 	configv1 "github.com/openshift/api/config/v1"
 	openshiftclientset "github.com/openshift/client-go/config/clientset/versioned"
 	clusterv1beta1 "github.com/stolostron/cluster-lifecycle-api/clusterinfo/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
+	corev1lister "k8s.io/client-go/listers/core/v1"
 	"k8s.io/klog/v2"
 	clusterv1alpha1lister "open-cluster-management.io/api/client/cluster/listers/cluster/v1alpha1"
 
@@ -21,10 +26,16 @@ type distributionInfoSyncer struct {
 	configV1Client       openshiftclientset.Interface
 	managedClusterClient kubernetes.Interface
 	claimLister          clusterv1alpha1lister.ClusterClaimLister
+	nodeLister           corev1lister.NodeLister
 }
 
 func (s *distributionInfoSyncer) sync(ctx context.Context, clusterInfo *clusterv1beta1.ManagedClusterInfo) error {
-	// currently we only support OCP in DistributionInfo
+	if err := s.syncKubeDistributionConditions(clusterInfo); err != nil {
+		return err
+	}
+
+	// currently we only support OCP in DistributionInfo, the distribution of other clusters is reported with the
+	// KubernetesDistribution condition
 	switch clusterInfo.Status.KubeVendor {
 	case clusterv1beta1.KubeVendorOpenShift:
 		return s.syncOCPDistributionInfo(ctx, &clusterInfo.Status)
@@ -33,6 +44,88 @@ func (s *distributionInfoSyncer) sync(ctx context.Context, clusterInfo *clusterv
 	return nil
 }
 
+// syncKubeDistributionConditions reports the KubeVersionSkewed condition and the KubernetesDistribution condition of
+// the non-OpenShift clusters, the conditions are not changed until the kube-apiserver version is reported by the
+// cluster claimer.
+func (s *distributionInfoSyncer) syncKubeDistributionConditions(clusterInfo *clusterv1beta1.ManagedClusterInfo) error {
+	kubeVersion, err := s.claimLister.Get(clusterclaim.ClaimOCMKubeVersion)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	nodes, err := s.nodeLister.List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("failed to list nodes, %v", err)
+	}
+
+	kubeDistributionInfo := clusterclaim.NewKubernetesDistributionInfo(kubeVersion.Spec.Value, nodes)
+	meta.SetStatusCondition(&clusterInfo.Status.Conditions, kubeVersionSkewedCondition(kubeDistributionInfo))
+
+	if clusterInfo.Status.KubeVendor == clusterv1beta1.KubeVendorOpenShift {
+		meta.RemoveStatusCondition(&clusterInfo.Status.Conditions, helpers.ConditionKubernetesDistribution)
+		return nil
+	}
+
+	meta.SetStatusCondition(&clusterInfo.Status.Conditions, kubeDistributionCondition(kubeDistributionInfo))
+	return nil
+}
+
+// kubeDistributionCondition reports the distribution, the server version and the kubelet and container runtime
+// versions of the nodes, the nodes with the same versions are grouped. The versions of each node are reported with
+// the labels of the node in the NodeList.
+func kubeDistributionCondition(info *clusterclaim.KubernetesDistributionInfo) metav1.Condition {
+	nodeCounts := map[string]int{}
+	for _, node := range info.Nodes {
+		nodeCounts[fmt.Sprintf("kubelet %s and %s", node.KubeletVersion, node.ContainerRuntimeVersion)]++
+	}
+
+	nodeVersions := []string{}
+	for versions, count := range nodeCounts {
+		nodeVersions = append(nodeVersions, fmt.Sprintf("%s on %d node(s)", versions, count))
+	}
+	sort.Strings(nodeVersions)
+
+	message := fmt.Sprintf("The kube-apiserver version is %s", info.ServerVersion)
+	if len(nodeVersions) > 0 {
+		message = fmt.Sprintf("%s, %s", message, strings.Join(nodeVersions, ", "))
+	}
+
+	return metav1.Condition{
+		Type:    helpers.ConditionKubernetesDistribution,
+		Status:  metav1.ConditionTrue,
+		Reason:  info.Distribution,
+		Message: message,
+	}
+}
+
+func kubeVersionSkewedCondition(info *clusterclaim.KubernetesDistributionInfo) metav1.Condition {
+	skewedNodes := info.SkewedNodes()
+	if len(skewedNodes) == 0 {
+		return metav1.Condition{
+			Type:    helpers.ConditionKubeVersionSkewed,
+			Status:  metav1.ConditionFalse,
+			Reason:  "KubeletVersionSupported",
+			Message: fmt.Sprintf("The kubelet versions of nodes are supported by the kube-apiserver %s", info.ServerVersion),
+		}
+	}
+
+	nodes := []string{}
+	for _, node := range skewedNodes {
+		nodes = append(nodes, fmt.Sprintf("%s (%s)", node.Name, node.KubeletVersion))
+	}
+
+	return metav1.Condition{
+		Type:   helpers.ConditionKubeVersionSkewed,
+		Status: metav1.ConditionTrue,
+		Reason: "KubeletVersionSkewed",
+		Message: fmt.Sprintf("The kubelet versions of nodes %s are not supported by the kube-apiserver %s",
+			strings.Join(nodes, ", "), info.ServerVersion),
+	}
+}
+
 func (s *distributionInfoSyncer) syncOCPDistributionInfo(ctx context.Context, clusterInfoStatus *clusterv1beta1.ClusterInfoStatus) error {
 	lastAppliedAPIServerURL := clusterInfoStatus.DistributionInfo.OCP.LastAppliedAPIServerURL
 	clusterInfoStatus.DistributionInfo = clusterv1beta1.DistributionInfo{
@@ -0,0 +1,126 @@
+package clusterinfo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	clusterv1beta1 "github.com/stolostron/cluster-lifecycle-api/clusterinfo/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/labels"
+	corev1lister "k8s.io/client-go/listers/core/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	"github.com/stolostron/multicluster-controlplane/pkg/helpers"
+)
+
+// NodeListSyncInterval is the minimum interval between two syncs that are triggered by the node updates, the node
+// updates in the interval are merged into one sync.
+var NodeListSyncInterval = 30 * time.Second
+
+// nodeLabels are the labels that are reported in the NodeList, the roles of the node are reported with the
+// node role labels.
+var nodeLabels = []string{
+	helpers.LabelNodeRoleOldControlPlane,
+	helpers.LabelNodeRoleControlPlane,
+	helpers.LabelNodeRoleInfra,
+	helpers.LabelNodeRoleWorker,
+	corev1.LabelTopologyRegion,
+	corev1.LabelTopologyZone,
+	corev1.LabelFailureDomainBetaRegion,
+	corev1.LabelFailureDomainBetaZone,
+	corev1.LabelInstanceTypeStable,
+	corev1.LabelInstanceType,
+	corev1.LabelArchStable,
+	corev1.LabelOSStable,
+}
+
+type nodeListSyncer struct {
+	nodeLister corev1lister.NodeLister
+}
+
+func (s *nodeListSyncer) sync(ctx context.Context, clusterInfo *clusterv1beta1.ManagedClusterInfo) error {
+	nodes, err := s.nodeLister.List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("failed to list nodes, %v", err)
+	}
+
+	nodeList := []clusterv1beta1.NodeStatus{}
+	for _, node := range nodes {
+		nodeList = append(nodeList, NewNodeStatus(node))
+	}
+	sort.Slice(nodeList, func(i, j int) bool { return nodeList[i].Name < nodeList[j].Name })
+
+	clusterInfo.Status.NodeList = nodeList
+	return nil
+}
+
+// NewNodeStatus returns the status of the node that is reported in the NodeList, the labels include the kubelet and
+// container runtime versions, the capacity includes cpu, memory and the extended resources of the schedulable node,
+// and the conditions only include NodeReady.
+func NewNodeStatus(node *corev1.Node) clusterv1beta1.NodeStatus {
+	nodeStatus := clusterv1beta1.NodeStatus{
+		Name:       node.Name,
+		Labels:     map[string]string{},
+		Capacity:   clusterv1beta1.ResourceList{},
+		Conditions: []clusterv1beta1.NodeCondition{},
+	}
+
+	for _, key := range nodeLabels {
+		if value, ok := node.Labels[key]; ok {
+			nodeStatus.Labels[key] = value
+		}
+	}
+	// the NodeStatus has no version fields, so the versions are reported with the labels
+	if version := node.Status.NodeInfo.KubeletVersion; len(version) > 0 {
+		nodeStatus.Labels[helpers.NodeLabelKubeletVersion] = version
+	}
+	if version := node.Status.NodeInfo.ContainerRuntimeVersion; len(version) > 0 {
+		nodeStatus.Labels[helpers.NodeLabelContainerRuntimeVersion] = version
+	}
+
+	if cpu, ok := node.Status.Capacity[corev1.ResourceCPU]; ok {
+		nodeStatus.Capacity[clusterv1beta1.ResourceCPU] = cpu
+	}
+	if memory, ok := node.Status.Capacity[corev1.ResourceMemory]; ok {
+		nodeStatus.Capacity[clusterv1beta1.ResourceMemory] = memory
+	}
+	// the extended resources of the unschedulable nodes are not reported, the same as the registration agent that
+	// aggregates the cluster capacity with the schedulable nodes.
+	for name, quantity := range node.Status.Capacity {
+		if isExtendedResource(name) && !node.Spec.Unschedulable {
+			nodeStatus.Capacity[clusterv1.ResourceName(name)] = quantity
+		}
+	}
+
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			nodeStatus.Conditions = append(nodeStatus.Conditions, clusterv1beta1.NodeCondition{
+				Type:   condition.Type,
+				Status: condition.Status,
+			})
+		}
+	}
+
+	return nodeStatus
+}
+
+// isExtendedResource returns true if the resource is an extended resource (e.g. nvidia.com/gpu), a hugepages resource
+// or the ephemeral storage.
+func isExtendedResource(name corev1.ResourceName) bool {
+	if name == corev1.ResourceEphemeralStorage || strings.HasPrefix(string(name), corev1.ResourceHugePagesPrefix) {
+		return true
+	}
+
+	// the extended resources are the resources that are not in the kubernetes.io domain
+	return strings.Contains(string(name), "/") && !strings.Contains(string(name), corev1.ResourceDefaultNamespacePrefix)
+}
+
+// NodeStatusChanged returns true if the reported status of the node is changed, the heartbeat-only updates of the
+// node are ignored.
+func NodeStatusChanged(oldNode, newNode *corev1.Node) bool {
+	return !equality.Semantic.DeepEqual(NewNodeStatus(oldNode), NewNodeStatus(newNode))
+}
@@ -0,0 +1,98 @@
+package clusterinfo
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	"github.com/stolostron/multicluster-controlplane/pkg/helpers"
+)
+
+func TestIsExtendedResource(t *testing.T) {
+	cases := []struct {
+		name     corev1.ResourceName
+		expected bool
+	}{
+		{name: corev1.ResourceCPU, expected: false},
+		{name: corev1.ResourceMemory, expected: false},
+		{name: corev1.ResourcePods, expected: false},
+		{name: "kubernetes.io/batch-cpu", expected: false},
+		{name: corev1.ResourceEphemeralStorage, expected: true},
+		{name: "hugepages-1Gi", expected: true},
+		{name: "nvidia.com/gpu", expected: true},
+	}
+
+	for _, c := range cases {
+		t.Run(string(c.name), func(t *testing.T) {
+			if actual := isExtendedResource(c.name); actual != c.expected {
+				t.Errorf("expected %v, but got %v", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestNewNodeStatus(t *testing.T) {
+	newNode := func(unschedulable bool) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+			Spec:       corev1.NodeSpec{Unschedulable: unschedulable},
+			Status: corev1.NodeStatus{
+				NodeInfo: corev1.NodeSystemInfo{
+					KubeletVersion:          "v1.27.3",
+					ContainerRuntimeVersion: "containerd://1.7.2",
+				},
+				Capacity: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("4"),
+					corev1.ResourceMemory: resource.MustParse("16Gi"),
+					corev1.ResourcePods:   resource.MustParse("110"),
+					"nvidia.com/gpu":      resource.MustParse("2"),
+				},
+			},
+		}
+	}
+
+	cases := []struct {
+		name             string
+		node             *corev1.Node
+		expectedCapacity clusterv1.ResourceList
+	}{
+		{
+			name: "schedulable node",
+			node: newNode(false),
+			expectedCapacity: clusterv1.ResourceList{
+				clusterv1.ResourceCPU:    resource.MustParse("4"),
+				clusterv1.ResourceMemory: resource.MustParse("16Gi"),
+				"nvidia.com/gpu":         resource.MustParse("2"),
+			},
+		},
+		{
+			name: "unschedulable node",
+			node: newNode(true),
+			expectedCapacity: clusterv1.ResourceList{
+				clusterv1.ResourceCPU:    resource.MustParse("4"),
+				clusterv1.ResourceMemory: resource.MustParse("16Gi"),
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			nodeStatus := NewNodeStatus(c.node)
+			if len(nodeStatus.Capacity) != len(c.expectedCapacity) {
+				t.Fatalf("expected capacity %v, but got %v", c.expectedCapacity, nodeStatus.Capacity)
+			}
+			for name, quantity := range c.expectedCapacity {
+				if actual, ok := nodeStatus.Capacity[name]; !ok || !actual.Equal(quantity) {
+					t.Errorf("expected %s %v, but got %v", name, quantity, nodeStatus.Capacity)
+				}
+			}
+			if nodeStatus.Labels[helpers.NodeLabelKubeletVersion] != "v1.27.3" ||
+				nodeStatus.Labels[helpers.NodeLabelContainerRuntimeVersion] != "containerd://1.7.2" {
+				t.Errorf("expected the node versions in the labels, but got %v", nodeStatus.Labels)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package view
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	viewclient "github.com/stolostron/cluster-lifecycle-api/client/view/clientset/versioned"
+	viewinformer "github.com/stolostron/cluster-lifecycle-api/client/view/informers/externalversions/view/v1beta1"
+	viewlister "github.com/stolostron/cluster-lifecycle-api/client/view/listers/view/v1beta1"
+	viewv1beta1 "github.com/stolostron/cluster-lifecycle-api/view/v1beta1"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+)
+
+// defaultUpdateInterval is the interval that a view is refreshed if its update interval is not specified
+const defaultUpdateInterval = 30 * time.Second
+
+// viewController mirrors the resources on the managed cluster that are specified by the ManagedClusterViews in the
+// cluster namespace to the status of the views, the views are refreshed periodically.
+type viewController struct {
+	clusterName        string
+	viewClient         viewclient.Interface
+	viewLister         viewlister.ManagedClusterViewLister
+	spokeDynamicClient dynamic.Interface
+	spokeRestMapper    meta.RESTMapper
+}
+
+func NewViewController(
+	clusterName string,
+	viewClient viewclient.Interface,
+	viewInformer viewinformer.ManagedClusterViewInformer,
+	spokeDynamicClient dynamic.Interface,
+	spokeRestMapper meta.RESTMapper,
+	recorder events.Recorder,
+) factory.Controller {
+	controller := &viewController{
+		clusterName:        clusterName,
+		viewClient:         viewClient,
+		viewLister:         viewInformer.Lister(),
+		spokeDynamicClient: spokeDynamicClient,
+		spokeRestMapper:    spokeRestMapper,
+	}
+
+	return factory.New().WithSync(controller.sync).
+		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			return accessor.GetName()
+		}, viewInformer.Informer()).
+		ToController("ManagedClusterViewController", recorder)
+}
+
+func (c *viewController) sync(ctx context.Context, controllerContext factory.SyncContext) error {
+	viewName := controllerContext.QueueKey()
+	if viewName == "" || viewName == factory.DefaultQueueKey {
+		return nil
+	}
+
+	view, err := c.viewLister.ManagedClusterViews(c.clusterName).Get(viewName)
+	switch {
+	case errors.IsNotFound(err):
+		return nil
+	case err != nil:
+		return err
+	}
+
+	if !view.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	newView := view.DeepCopy()
+	result, cond := c.getResource(ctx, view.Spec.Scope)
+	newView.Status.Result = result
+	meta.SetStatusCondition(&newView.Status.Conditions, cond)
+
+	if !equality.Semantic.DeepEqual(view.Status, newView.Status) {
+		if _, err := c.viewClient.ViewV1beta1().ManagedClusterViews(c.clusterName).UpdateStatus(
+			ctx, newView, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+
+	interval := defaultUpdateInterval
+	if view.Spec.Scope.UpdateIntervalSeconds > 0 {
+		interval = time.Duration(view.Spec.Scope.UpdateIntervalSeconds) * time.Second
+	}
+	controllerContext.Queue().AddAfter(viewName, interval)
+	return nil
+}
+
+// getResource gets the resource of the view scope from the managed cluster, it returns the resource and the
+// Processing condition of the view.
+func (c *viewController) getResource(
+	ctx context.Context, scope viewv1beta1.ViewScope) (runtime.RawExtension, metav1.Condition) {
+	if len(scope.Name) == 0 {
+		return runtime.RawExtension{}, failedCondition(viewv1beta1.ReasonResourceNameInvalid,
+			"the name of the resource is required")
+	}
+
+	gvr, namespaced, err := c.resourceFor(scope)
+	if err != nil {
+		reason := viewv1beta1.ReasonResourceGVKInvalid
+		if len(scope.Resource) == 0 && len(scope.Kind) == 0 {
+			reason = viewv1beta1.ReasonResourceTypeInvalid
+		}
+		return runtime.RawExtension{}, failedCondition(reason, err.Error())
+	}
+
+	var resourceClient dynamic.ResourceInterface = c.spokeDynamicClient.Resource(gvr)
+	if namespaced {
+		resourceClient = c.spokeDynamicClient.Resource(gvr).Namespace(scope.Namespace)
+	}
+
+	obj, err := resourceClient.Get(ctx, scope.Name, metav1.GetOptions{})
+	if err != nil {
+		klog.V(4).Infof("failed to get resource %s %s/%s, %v", gvr, scope.Namespace, scope.Name, err)
+		return runtime.RawExtension{}, failedCondition(viewv1beta1.ReasonGetResourceFailed, err.Error())
+	}
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return runtime.RawExtension{}, failedCondition(viewv1beta1.ReasonGetResourceFailed, err.Error())
+	}
+
+	return runtime.RawExtension{Raw: raw}, metav1.Condition{
+		Type:    viewv1beta1.ConditionViewProcessing,
+		Status:  metav1.ConditionTrue,
+		Reason:  viewv1beta1.ReasonGetResource,
+		Message: "Watching resources successfully",
+	}
+}
+
+// resourceFor maps the view scope to the resource on the managed cluster, the resource of the scope is preferred to
+// its kind. It also returns whether the resource is namespaced.
+func (c *viewController) resourceFor(scope viewv1beta1.ViewScope) (schema.GroupVersionResource, bool, error) {
+	var mapping *meta.RESTMapping
+	switch {
+	case len(scope.Resource) > 0:
+		gvr, err := c.spokeRestMapper.ResourceFor(schema.GroupVersionResource{
+			Group:    scope.Group,
+			Version:  scope.Version,
+			Resource: scope.Resource,
+		})
+		if err != nil {
+			return schema.GroupVersionResource{}, false, err
+		}
+		gvk, err := c.spokeRestMapper.KindFor(gvr)
+		if err != nil {
+			return schema.GroupVersionResource{}, false, err
+		}
+		mapping, err = c.spokeRestMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return schema.GroupVersionResource{}, false, err
+		}
+	case len(scope.Kind) > 0:
+		var err error
+		versions := []string{}
+		if len(scope.Version) > 0 {
+			versions = append(versions, scope.Version)
+		}
+		mapping, err = c.spokeRestMapper.RESTMapping(schema.GroupKind{Group: scope.Group, Kind: scope.Kind}, versions...)
+		if err != nil {
+			return schema.GroupVersionResource{}, false, err
+		}
+	default:
+		return schema.GroupVersionResource{}, false, fmt.Errorf("the resource or kind of the resource is required")
+	}
+
+	return mapping.Resource, mapping.Scope.Name() == meta.RESTScopeNameNamespace, nil
+}
+
+func failedCondition(reason, message string) metav1.Condition {
+	return metav1.Condition{
+		Type:    viewv1beta1.ConditionViewProcessing,
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	}
+}
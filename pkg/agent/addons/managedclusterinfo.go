@@ -8,16 +8,23 @@ import (
 	openshiftoauthclientset "github.com/openshift/client-go/oauth/clientset/versioned"
 	"github.com/openshift/library-go/pkg/controller/factory"
 
+	actionclient "github.com/stolostron/cluster-lifecycle-api/client/action/clientset/versioned"
+	actioninformers "github.com/stolostron/cluster-lifecycle-api/client/action/informers/externalversions"
 	clusterinfoclient "github.com/stolostron/cluster-lifecycle-api/client/clusterinfo/clientset/versioned"
 	clusterinfoinformers "github.com/stolostron/cluster-lifecycle-api/client/clusterinfo/informers/externalversions"
 	clusterinfoinformer "github.com/stolostron/cluster-lifecycle-api/client/clusterinfo/informers/externalversions/clusterinfo/v1beta1"
+	viewclient "github.com/stolostron/cluster-lifecycle-api/client/view/clientset/versioned"
+	viewinformers "github.com/stolostron/cluster-lifecycle-api/client/view/informers/externalversions"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -27,14 +34,18 @@ import (
 	clusterv1alpha1informer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1alpha1"
 	"open-cluster-management.io/multicluster-controlplane/pkg/util"
 
+	"github.com/stolostron/multicluster-controlplane/pkg/agent/addons/controllers/action"
 	"github.com/stolostron/multicluster-controlplane/pkg/agent/addons/controllers/clusterclaim"
 	"github.com/stolostron/multicluster-controlplane/pkg/agent/addons/controllers/clusterinfo"
+	"github.com/stolostron/multicluster-controlplane/pkg/agent/addons/controllers/view"
+	"github.com/stolostron/multicluster-controlplane/pkg/agent/metrics"
 )
 
 func StartManagedClusterInfoAgent(
 	ctx context.Context,
 	clusterName string,
 	selfManagementEnabled bool,
+	resyncPeriod time.Duration,
 	hubKubeConfig, spokeKubeConfig *rest.Config,
 	restMapper meta.RESTMapper,
 	kubeInformerFactory informers.SharedInformerFactory,
@@ -65,9 +76,24 @@ func StartManagedClusterInfoAgent(
 		return err
 	}
 
+	viewClient, err := viewclient.NewForConfig(hubKubeConfig)
+	if err != nil {
+		return err
+	}
+
+	actionClient, err := actionclient.NewForConfig(hubKubeConfig)
+	if err != nil {
+		return err
+	}
+
+	spokeDynamicClient, err := dynamic.NewForConfig(spokeKubeConfig)
+	if err != nil {
+		return err
+	}
+
 	clusterInfoInformerFactory := clusterinfoinformers.NewSharedInformerFactoryWithOptions(
 		clusterInfoClient,
-		10*time.Minute,
+		resyncPeriod,
 		clusterinfoinformers.WithNamespace(clusterName),
 	)
 
@@ -82,10 +108,22 @@ func StartManagedClusterInfoAgent(
 			return err
 		}
 
-		kubeInformerFactory = informers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
-		clusterInformerFactory = clusterinformers.NewSharedInformerFactory(clusterClient, 10*time.Minute)
+		kubeInformerFactory = informers.NewSharedInformerFactory(kubeClient, resyncPeriod)
+		clusterInformerFactory = clusterinformers.NewSharedInformerFactory(clusterClient, resyncPeriod)
 	}
 
+	viewInformerFactory := viewinformers.NewSharedInformerFactoryWithOptions(
+		viewClient,
+		resyncPeriod,
+		viewinformers.WithNamespace(clusterName),
+	)
+
+	actionInformerFactory := actioninformers.NewSharedInformerFactoryWithOptions(
+		actionClient,
+		resyncPeriod,
+		actioninformers.WithNamespace(clusterName),
+	)
+
 	clusterInfoInformer := clusterInfoInformerFactory.Internal().V1beta1().ManagedClusterInfos()
 	nodeInformer := kubeInformerFactory.Core().V1().Nodes()
 	claimInformer := clusterInformerFactory.Cluster().V1alpha1().ClusterClaims()
@@ -95,6 +133,7 @@ func StartManagedClusterInfoAgent(
 		ManagedClusterClient:     kubeClient,
 		ConfigV1Client:           ocpClient,
 		ClaimLister:              claimInformer.Lister(),
+		NodeLister:               nodeInformer.Lister(),
 		ManagedClusterInfoList:   clusterInfoInformer.Lister(),
 		ClusterName:              clusterName,
 	}
@@ -102,6 +141,7 @@ func StartManagedClusterInfoAgent(
 	clusterClaimer := clusterclaim.ClusterClaimer{
 		ClusterName:                     clusterName,
 		KubeClient:                      kubeClient,
+		NodeLister:                      nodeInformer.Lister(),
 		ConfigV1Client:                  ocpClient,
 		OauthV1Client:                   ocpOauthClient,
 		ManagedClusterInfoList:          clusterInfoInformer.Lister(),
@@ -112,6 +152,16 @@ func StartManagedClusterInfoAgent(
 	clusterClaimReconciler := &clusterclaim.ClusterClaimReconciler{
 		ClusterClient:     clusterClient,
 		ListClusterClaims: clusterClaimer.List,
+		ClaimProviders: []clusterclaim.ClaimProvider{
+			&clusterclaim.ConfigMapClaimProvider{
+				KubeClient:         kubeClient,
+				DynamicClient:      spokeDynamicClient,
+				Mapper:             restMapper,
+				NodeLister:         nodeInformer.Lister(),
+				ConfigMapNamespace: clusterclaim.CustomClaimsConfigMapNamespace,
+				ConfigMapName:      clusterclaim.CustomClaimsConfigMapName,
+			},
+		},
 	}
 
 	workmgrController := newWorkMgrController(
@@ -122,13 +172,36 @@ func StartManagedClusterInfoAgent(
 		claimInformer,
 	)
 
+	// the views and actions are served by the spoke clients of the managed cluster info agent
+	viewController := view.NewViewController(
+		clusterName,
+		viewClient,
+		viewInformerFactory.View().V1beta1().ManagedClusterViews(),
+		spokeDynamicClient,
+		restMapper,
+		util.NewLoggingRecorder("managedclusterview-controller"),
+	)
+
+	actionController := action.NewActionController(
+		clusterName,
+		actionClient,
+		actionInformerFactory.Action().V1beta1().ManagedClusterActions(),
+		spokeDynamicClient,
+		restMapper,
+		util.NewLoggingRecorder("managedclusteraction-controller"),
+	)
+
 	go clusterInfoInformerFactory.Start(ctx.Done())
+	go viewInformerFactory.Start(ctx.Done())
+	go actionInformerFactory.Start(ctx.Done())
 	if selfManagementEnabled {
 		go kubeInformerFactory.Start(ctx.Done())
 		go clusterInformerFactory.Start(ctx.Done())
 	}
 
 	go workmgrController.Run(ctx, 1)
+	go viewController.Run(ctx, 1)
+	go actionController.Run(ctx, 1)
 
 	return nil
 }
@@ -148,17 +221,49 @@ func newWorkMgrController(
 		clusterInfoReconciler:  clusterInfoReconciler,
 		clusterClaimReconciler: clusterClaimReconciler,
 	}
+
+	recorder := util.NewLoggingRecorder("workmgr-controller")
+	syncCtx := factory.NewSyncContext("WorkerManagerController", recorder)
+
+	// the nodes are updated frequently by the heartbeats, so the heartbeat-only updates are ignored, and the other
+	// updates are merged into one sync in the NodeListSyncInterval to avoid updating the hub frequently.
+	nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			syncCtx.Queue().Add(factory.DefaultQueueKey)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldNode, ok := oldObj.(*corev1.Node)
+			if !ok {
+				return
+			}
+			newNode, ok := newObj.(*corev1.Node)
+			if !ok {
+				return
+			}
+			if clusterinfo.NodeStatusChanged(oldNode, newNode) {
+				syncCtx.Queue().AddAfter(factory.DefaultQueueKey, clusterinfo.NodeListSyncInterval)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			syncCtx.Queue().Add(factory.DefaultQueueKey)
+		},
+	})
+
 	return factory.New().WithSync(controller.sync).
-		WithInformers(nodeInformer.Informer(), clusterInfoInformer.Informer(), claimInformer.Informer()).
-		ToController("WorkerManagerController", util.NewLoggingRecorder("workmgr-controller"))
+		WithSyncContext(syncCtx).
+		WithBareInformers(nodeInformer.Informer()).
+		WithInformers(clusterInfoInformer.Informer(), claimInformer.Informer()).
+		ToController("WorkerManagerController", recorder)
 }
 
 func (c *workmgrController) sync(ctx context.Context, controllerContext factory.SyncContext) error {
 	errs := []error{}
 	if _, err := c.clusterInfoReconciler.Reconcile(ctx, reconcile.Request{}); err != nil {
+		metrics.RecordSyncError("clusterinfo")
 		errs = append(errs, err)
 	}
 	if _, err := c.clusterClaimReconciler.Reconcile(ctx, reconcile.Request{}); err != nil {
+		metrics.RecordSyncError("clusterclaim")
 		errs = append(errs, err)
 	}
 
@@ -2,6 +2,7 @@ package addons
 
 import (
 	"context"
+	"fmt"
 	"os"
 
 	depclient "github.com/stolostron/kubernetes-dependency-watches/client"
@@ -18,28 +19,31 @@ import (
 	"k8s.io/klog/v2"
 
 	"open-cluster-management.io/config-policy-controller/controllers"
+	"open-cluster-management.io/governance-policy-framework-addon/controllers/gatekeepersync"
 	"open-cluster-management.io/governance-policy-framework-addon/controllers/secretsync"
 	"open-cluster-management.io/governance-policy-framework-addon/controllers/specsync"
 	"open-cluster-management.io/governance-policy-framework-addon/controllers/statussync"
 	"open-cluster-management.io/governance-policy-framework-addon/controllers/templatesync"
+	frameworkutils "open-cluster-management.io/governance-policy-framework-addon/controllers/utils"
+	"open-cluster-management.io/multicluster-controlplane/pkg/features"
 
 	ctrl "sigs.k8s.io/controller-runtime"
-)
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
 
-type PolicyAgentConfig struct {
-	DecryptionConcurrency uint8
-	EvaluationConcurrency uint8
-	EnableMetrics         bool
-	Frequency             uint
-}
+	"github.com/stolostron/multicluster-controlplane/pkg/agent/addons/controllers/certificatepolicy"
+	"github.com/stolostron/multicluster-controlplane/pkg/config"
+	"github.com/stolostron/multicluster-controlplane/pkg/feature"
+)
 
 func StartPolicyAgent(
 	ctx context.Context,
 	scheme *runtime.Scheme,
 	clusterName string,
 	hubKubeConfig, hostingKubeConfig, spokeKubeConfig *rest.Config,
+	hostedMode bool,
 	hubManager, hostingManager ctrl.Manager,
-	config *PolicyAgentConfig) error {
+	config *config.PolicyAgentConfig,
+	addHealthzCheck func(name string, check healthz.Checker)) error {
 	instanceName, _ := os.Hostname() // on an error, instanceName will be empty, which is ok
 
 	hubKubeClient, err := kubernetes.NewForConfig(hubKubeConfig)
@@ -72,6 +76,13 @@ func StartPolicyAgent(
 		&clientcorev1.EventSinkImpl{Interface: spokeClient.CoreV1().Events(clusterName)},
 	)
 
+	// the broadcasters are stopped with the context, so they are not leaked by a failed setup
+	go func() {
+		<-ctx.Done()
+		hubEventBroadcaster.Shutdown()
+		spokeEventBroadcaster.Shutdown()
+	}()
+
 	// create target namespace if it doesn't exist
 	targetNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: clusterName}}
 	if _, err := hostingKubeClient.CoreV1().Namespaces().Create(
@@ -100,6 +111,22 @@ func StartPolicyAgent(
 		reconciler.PeriodicallyExecConfigPolicies(ctx, config.Frequency, hostingManager.Elected())
 	}()
 
+	if features.DefaultAgentMutableFeatureGate.Enabled(feature.CertificatePolicy) {
+		klog.Info("starting certificate policy controller")
+		if err := (&certificatepolicy.CertificatePolicyReconciler{
+			Client: hostingManager.GetClient(),
+			ComplianceEventSender: frameworkutils.ComplianceEventSender{
+				ClusterNamespace: clusterName,
+				ClientSet:        hostingKubeClient,
+				ControllerName:   certificatepolicy.ControllerName,
+				InstanceName:     instanceName,
+			},
+			TargetK8sClient: spokeClient,
+		}).SetupWithManager(hostingManager); err != nil {
+			return err
+		}
+	}
+
 	if err := (&specsync.PolicyReconciler{
 		HubClient:       hubManager.GetClient(),
 		ManagedClient:   hostingManager.GetClient(),
@@ -147,15 +174,38 @@ func StartPolicyAgent(
 		Clientset:        kubernetes.NewForConfigOrDie(hostingManager.GetConfig()),
 		InstanceName:     instanceName,
 	}
+	watcherErr := make(chan error, 1)
 	go func() {
 		err := watcher.Start(ctx)
 		if err != nil {
-			panic(err)
+			klog.Errorf("failed to start the policy template dynamic watcher, %v", err)
 		}
+		watcherErr <- err
 	}()
 
 	// Wait until the dynamic watcher has started.
-	<-watcher.Started()
+	select {
+	case <-watcher.Started():
+	case err := <-watcherErr:
+		return fmt.Errorf("the policy template dynamic watcher is stopped, %v", err)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	// the policy templates are synced to the management cluster in the hosted mode, so the gatekeeper constraints
+	// are not synced to the managed cluster, the gatekeeper integration is disabled in the hosted mode
+	gatekeeperInstalled := false
+	if hostedMode {
+		klog.Info("the gatekeeper integration of the policy agent is disabled in the hosted mode")
+	} else {
+		gatekeeperInstalled, err = startGatekeeperSync(ctx, clusterName, instanceName, hostingManager, addHealthzCheck)
+		if err != nil {
+			return err
+		}
+	}
+	// the gatekeeper constraint templates and constraints in the policies are synced only when the gatekeeper is
+	// installed, otherwise the policies report the gatekeeper integration is disabled
+	templateReconciler.DisableGkSync = !gatekeeperInstalled
 
 	klog.Info("starting policy template sync controller")
 	if err := templateReconciler.Setup(hostingManager, depEvents); err != nil {
@@ -164,3 +214,74 @@ func StartPolicyAgent(
 
 	return nil
 }
+
+// startGatekeeperSync starts the gatekeeper constraint status sync controller if the gatekeeper is installed on the
+// managed cluster that the policy templates are synced to in the default mode, the controller reports the audit violations of the gatekeeper
+// constraints as the compliance of their policies. The installation of the gatekeeper is watched by a liveness check,
+// the agent is restarted to enable or disable the gatekeeper integration once the installation is changed.
+func startGatekeeperSync(
+	ctx context.Context,
+	clusterName, instanceName string,
+	hostingManager ctrl.Manager,
+	addHealthzCheck func(name string, check healthz.Checker)) (bool, error) {
+	hostingDynamicClient, err := dynamic.NewForConfig(hostingManager.GetConfig())
+	if err != nil {
+		return false, err
+	}
+
+	gkHealthCheck, gatekeeperInstalled, err := gatekeepersync.GatekeeperInstallationChecker(ctx, hostingDynamicClient)
+	if err != nil {
+		return false, err
+	}
+	if addHealthzCheck != nil {
+		addHealthzCheck("gatekeeper-installation", gkHealthCheck)
+	}
+
+	if !gatekeeperInstalled {
+		klog.Info("gatekeeper is not installed, the gatekeeper integration of the policy agent is disabled")
+		return false, nil
+	}
+
+	constraintsReconciler, constraintEvents := depclient.NewControllerRuntimeSource()
+
+	constraintsWatcher, err := depclient.New(hostingManager.GetConfig(), constraintsReconciler, nil)
+	if err != nil {
+		return false, err
+	}
+
+	constraintsWatcherErr := make(chan error, 1)
+	go func() {
+		err := constraintsWatcher.Start(ctx)
+		if err != nil {
+			klog.Errorf("failed to start the gatekeeper constraints watcher, %v", err)
+		}
+		constraintsWatcherErr <- err
+	}()
+
+	// Wait until the constraints watcher has started.
+	select {
+	case <-constraintsWatcher.Started():
+	case err := <-constraintsWatcherErr:
+		return false, fmt.Errorf("the gatekeeper constraints watcher is stopped, %v", err)
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+
+	klog.Info("starting gatekeeper constraint status sync controller")
+	if err := (&gatekeepersync.GatekeeperConstraintReconciler{
+		Client: hostingManager.GetClient(),
+		ComplianceEventSender: frameworkutils.ComplianceEventSender{
+			ClusterNamespace: clusterName,
+			ClientSet:        kubernetes.NewForConfigOrDie(hostingManager.GetConfig()),
+			ControllerName:   gatekeepersync.ControllerName,
+			InstanceName:     instanceName,
+		},
+		DynamicClient:      hostingDynamicClient,
+		ConstraintsWatcher: constraintsWatcher,
+		Scheme:             hostingManager.GetScheme(),
+	}).SetupWithManager(hostingManager, constraintEvents); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
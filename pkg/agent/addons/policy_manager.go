@@ -0,0 +1,143 @@
+package addons
+
+import (
+	"fmt"
+	"strings"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+
+	configpolicyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+	"open-cluster-management.io/config-policy-controller/controllers"
+	configcommon "open-cluster-management.io/config-policy-controller/pkg/common"
+	"open-cluster-management.io/governance-policy-framework-addon/controllers/gatekeepersync"
+	"open-cluster-management.io/governance-policy-framework-addon/controllers/secretsync"
+	policyv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+var correlatorOptions = record.CorrelatorOptions{
+	// This essentially disables event aggregation of the same events but with different messages.
+	MaxIntervalInSeconds: 1,
+	// This is the default spam key function except it adds the reason and message as well.
+	// https://github.com/kubernetes/client-go/blob/v0.23.3/tools/record/events_cache.go#L70-L82
+	SpamKeyFunc: func(event *corev1.Event) string {
+		return strings.Join(
+			[]string{
+				event.Source.Component,
+				event.Source.Host,
+				event.InvolvedObject.Kind,
+				event.InvolvedObject.Namespace,
+				event.InvolvedObject.Name,
+				string(event.InvolvedObject.UID),
+				event.InvolvedObject.APIVersion,
+				event.Reason,
+				event.Message,
+			},
+			"",
+		)
+	},
+}
+
+// NewPolicyHubManager creates the controller-runtime manager of the policy addon agent for the hub.
+func NewPolicyHubManager(scheme *runtime.Scheme, hubKubeConfig *rest.Config, clusterName string) (manager.Manager, error) {
+	mgr, err := ctrl.NewManager(hubKubeConfig, ctrl.Options{
+		Scheme:             scheme,
+		Namespace:          clusterName,
+		MetricsBindAddress: "0", // the metrics are served by the agent metrics server
+		Cache: cache.Options{
+			ByObject: map[client.Object]cache.ByObject{
+				&corev1.Secret{}: {
+					Field: fields.SelectorFromSet(fields.Set{"metadata.name": secretsync.SecretName}),
+				},
+				&policyv1.Policy{}: {
+					Field:     fields.SelectorFromSet(fields.Set{"metadata.namespace": clusterName}),
+					Transform: transformer,
+				},
+				&corev1.Event{}: {
+					Field: fields.SelectorFromSet(fields.Set{"metadata.namespace": clusterName}),
+				},
+			},
+			Namespaces: []string{clusterName},
+		},
+		// Override the EventBroadcaster so that the spam filter will not ignore events for the policy but with
+		// different messages if a large amount of events for that policy are sent in a short time.
+		EventBroadcaster: record.NewBroadcasterWithCorrelatorOptions(correlatorOptions),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mgr, nil
+}
+
+// NewPolicyHostingManager creates the controller-runtime manager of the policy addon agent for the hosting cluster.
+func NewPolicyHostingManager(scheme *runtime.Scheme, hostingKubeConfig *rest.Config, clusterName string) (manager.Manager, error) {
+	ctrlKey, err := configcommon.GetOperatorNamespacedName()
+	if err != nil {
+		return nil, err
+	}
+
+	mgr, err := ctrl.NewManager(hostingKubeConfig, ctrl.Options{
+		Scheme:             scheme,
+		MetricsBindAddress: "0", // the metrics are served by the agent metrics server
+		Cache: cache.Options{
+			ByObject: map[client.Object]cache.ByObject{
+				&apiextensionsv1.CustomResourceDefinition{}: {
+					Field: fields.SelectorFromSet(fields.Set{"metadata.name": controllers.CRDName}),
+				},
+				&appsv1.Deployment{}: {
+					Field: fields.SelectorFromSet(fields.Set{
+						"metadata.namespace": ctrlKey.Namespace,
+						"metadata.name":      ctrlKey.Name,
+					}),
+				},
+				&configpolicyv1.ConfigurationPolicy{}: {
+					Field:     fields.SelectorFromSet(fields.Set{"metadata.namespace": clusterName}),
+					Transform: transformer,
+				},
+				&policyv1.Policy{}: {
+					Field:     fields.SelectorFromSet(fields.Set{"metadata.namespace": clusterName}),
+					Transform: transformer,
+				},
+				&corev1.Event{}: {
+					Field: fields.SelectorFromSet(fields.Set{"metadata.namespace": clusterName}),
+				},
+				// the gatekeeper constraint status sync controller checks whether the gatekeeper webhook is enabled
+				&admissionregistrationv1.ValidatingWebhookConfiguration{}: {
+					Field: fields.SelectorFromSet(fields.Set{"metadata.name": gatekeepersync.GatekeeperWebhookName}),
+				},
+			},
+			Namespaces: []string{ctrlKey.Namespace, clusterName},
+		},
+		ClientDisableCacheFor: []client.Object{&corev1.Secret{}},
+		// Override the EventBroadcaster so that the spam filter will not ignore events for the policy but with
+		// different messages if a large amount of events for that policy are sent in a short time.
+		EventBroadcaster: record.NewBroadcasterWithCorrelatorOptions(correlatorOptions),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mgr, nil
+}
+
+// remove unused fields beforing pushing to cache to optimize memory usage
+func transformer(obj interface{}) (interface{}, error) {
+	k8sObj, ok := obj.(client.Object)
+	if !ok {
+		return nil, fmt.Errorf("invalid type")
+	}
+	k8sObj.SetManagedFields(nil)
+	return k8sObj, nil
+}
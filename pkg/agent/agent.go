@@ -3,42 +3,40 @@ package agent
 import (
 	"context"
 	"fmt"
-	"strings"
+	"net/http"
+	"os"
+	"path"
+	"sync"
+	"sync/atomic"
 
 	gktemplatesv1 "github.com/open-policy-agent/frameworks/constraint/pkg/apis/templates/v1"
 	gktemplatesv1beta1 "github.com/open-policy-agent/frameworks/constraint/pkg/apis/templates/v1beta1"
 	clusterinfov1beta1 "github.com/stolostron/cluster-lifecycle-api/clusterinfo/v1beta1"
 
-	appsv1 "k8s.io/api/apps/v1"
-	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
-	"k8s.io/apimachinery/pkg/fields"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
 	kubescheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	configpolicyv1 "open-cluster-management.io/config-policy-controller/api/v1"
-	"open-cluster-management.io/config-policy-controller/controllers"
-	configcommon "open-cluster-management.io/config-policy-controller/pkg/common"
-	"open-cluster-management.io/governance-policy-framework-addon/controllers/secretsync"
 	policyv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
 	"open-cluster-management.io/multicluster-controlplane/pkg/agent"
 	"open-cluster-management.io/multicluster-controlplane/pkg/features"
 
-	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/cache"
-	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/manager"
-
-	"github.com/stolostron/multicluster-controlplane/pkg/agent/addons"
+	"github.com/stolostron/multicluster-controlplane/pkg/addon"
+	// register the built-in addons
+	_ "github.com/stolostron/multicluster-controlplane/pkg/addon/builtin"
 	"github.com/stolostron/multicluster-controlplane/pkg/agent/addons/manifests"
-	"github.com/stolostron/multicluster-controlplane/pkg/feature"
+	"github.com/stolostron/multicluster-controlplane/pkg/agent/metrics"
+	"github.com/stolostron/multicluster-controlplane/pkg/config"
 	"github.com/stolostron/multicluster-controlplane/pkg/helpers"
 )
 
@@ -55,59 +53,54 @@ func init() {
 	utilruntime.Must(gktemplatesv1beta1.AddToScheme(scheme))
 }
 
+// bootstrapTokenFile is the token of the bootstrap kubeconfig, see the bootstrap-kubeconfig secret in the import
+// manifests.
+const bootstrapTokenFile = "token"
+
 var agentRequiredCRDFiles = []string{
 	"crds/clusters.open-cluster-management.io_clusterclaims.crd.yaml",
 	"crds/work.open-cluster-management.io_appliedmanifestworks.crd.yaml",
 }
 
-var policyRequiredCRDFiles = []string{
-	"crds/policy.open-cluster-management.io_configurationpolicies.crd.yaml",
-	"crds/policy.open-cluster-management.io_policies.crd.yaml",
-}
-
-var correlatorOptions = record.CorrelatorOptions{
-	// This essentially disables event aggregation of the same events but with different messages.
-	MaxIntervalInSeconds: 1,
-	// This is the default spam key function except it adds the reason and message as well.
-	// https://github.com/kubernetes/client-go/blob/v0.23.3/tools/record/events_cache.go#L70-L82
-	SpamKeyFunc: func(event *corev1.Event) string {
-		return strings.Join(
-			[]string{
-				event.Source.Component,
-				event.Source.Host,
-				event.InvolvedObject.Kind,
-				event.InvolvedObject.Namespace,
-				event.InvolvedObject.Name,
-				string(event.InvolvedObject.UID),
-				event.InvolvedObject.APIVersion,
-				event.Reason,
-				event.Message,
-			},
-			"",
-		)
-	},
+// agentHostingCRDFiles are required on the cluster that the agent is running on, the setup status of the addon agents
+// is reported with the AddOnStatus there.
+var agentHostingCRDFiles = []string{
+	"crds/controlplane.open-cluster-management.io_addonstatuses.crd.yaml",
 }
 
 type AgentOptions struct {
 	*agent.AgentOptions
-	*addons.PolicyAgentConfig
+	// AddOnsConfig is the config of the addon agents, it is set by the agent flags or the addons config of the
+	// controlplane.
+	AddOnsConfig *config.AddOnsConfig
+	// MetricsBindAddress is the address that the agent metrics and health probes are served on, the server is
+	// disabled if it is empty or "0".
+	MetricsBindAddress    string
 	hubKubeConfig         *rest.Config
 	selfManagementEnabled bool
 	clusterName           string
+	metricsServer         *metrics.Server
+	addOnsStarted         atomic.Bool
+	// failedAddOns records the setup errors of the failed addons
+	failedAddOns sync.Map
+	// hostingDynamicClient reports the AddOnStatus of the addon agents
+	hostingDynamicClient dynamic.Interface
 }
 
 func NewAgentOptions() *AgentOptions {
 	return &AgentOptions{
-		AgentOptions: agent.NewAgentOptions(),
-		PolicyAgentConfig: &addons.PolicyAgentConfig{
-			// TODO pass them via parameters
-			DecryptionConcurrency: 5,
-			EvaluationConcurrency: 2,
-			Frequency:             10,
-		},
+		AgentOptions:  agent.NewAgentOptions(),
+		AddOnsConfig:  config.NewDefaultAddOnsConfig(),
+		metricsServer: metrics.NewServer(),
 	}
 }
 
+// WithAddOnsConfig applies the agent tunables of the addons config to the agent.
+func (a *AgentOptions) WithAddOnsConfig(addOnsConfig *config.AddOnsConfig) *AgentOptions {
+	a.AddOnsConfig = addOnsConfig
+	return a
+}
+
 func (a *AgentOptions) WithHubKubeConfig(hubKubeConfig *rest.Config) *AgentOptions {
 	a.hubKubeConfig = hubKubeConfig
 	return a
@@ -123,6 +116,35 @@ func (a *AgentOptions) WithSelfManagementEnabled(enabled bool) *AgentOptions {
 	return a
 }
 
+// RunMetricsServer serves the agent metrics and health probes on the metrics bind address until the context is
+// done, the agent is ready after its addons are started and the hub is reachable.
+func (a *AgentOptions) RunMetricsServer(ctx context.Context) error {
+	if len(a.MetricsBindAddress) == 0 || a.MetricsBindAddress == "0" {
+		return nil
+	}
+
+	a.metricsServer.AddReadyzCheck("addons", func(_ *http.Request) error {
+		if !a.addOnsStarted.Load() {
+			return fmt.Errorf("the agent addons are not started")
+		}
+
+		errs := []error{}
+		a.failedAddOns.Range(func(name, err any) bool {
+			errs = append(errs, fmt.Errorf("failed to setup %s addon, %v", name, err))
+			return true
+		})
+		return helpers.NewMultiLineAggregate(errs)
+	})
+	a.metricsServer.AddDebugHandler("config", func() (any, error) {
+		return map[string]any{
+			"resyncPeriod": a.AddOnsConfig.ResyncPeriod.Duration.String(),
+			"policy":       a.AddOnsConfig.Policy.Agent,
+		}, nil
+	})
+
+	return a.metricsServer.Start(ctx, a.MetricsBindAddress)
+}
+
 func (a *AgentOptions) RunAddOns(ctx context.Context) error {
 	var err error
 
@@ -133,6 +155,13 @@ func (a *AgentOptions) RunAddOns(ctx context.Context) error {
 		if err != nil {
 			return err
 		}
+
+		// the token of the bootstrap kubeconfig is refreshed by the controlplane, it is reloaded from the token
+		// file beside the bootstrap kubeconfig if the file exists.
+		tokenFile := path.Join(path.Dir(a.RegistrationAgent.BootstrapKubeconfig), bootstrapTokenFile)
+		if _, err := os.Stat(tokenFile); err == nil {
+			hubKubeConfig.BearerTokenFile = tokenFile
+		}
 	}
 
 	// in hosted mode, the hostingKubeConfig is for the management cluster.
@@ -161,161 +190,102 @@ func (a *AgentOptions) RunAddOns(ctx context.Context) error {
 	if err := helpers.EnsureCRDs(ctx, scheme, spokeCRDClient, manifests.AgentCRDFiles, agentRequiredCRDFiles...); err != nil {
 		return err
 	}
+	if err := helpers.EnsureCRDs(ctx, scheme, hostingCRDClient, manifests.AgentCRDFiles, agentHostingCRDFiles...); err != nil {
+		return err
+	}
 
-	if err := helpers.EnsureCRDs(ctx, scheme, hostingCRDClient, manifests.AgentCRDFiles, policyRequiredCRDFiles...); err != nil {
+	a.hostingDynamicClient, err = dynamic.NewForConfig(hostingKubeConfig)
+	if err != nil {
+		return err
+	}
+
+	hubKubeClient, err := kubernetes.NewForConfig(hubKubeConfig)
+	if err != nil {
 		return err
 	}
+	a.metricsServer.AddReadyzCheck("hub-connectivity", func(_ *http.Request) error {
+		if _, err := hubKubeClient.Discovery().ServerVersion(); err != nil {
+			return fmt.Errorf("failed to connect to the hub, %v", err)
+		}
+		return nil
+	})
 
 	clusterName := a.clusterName
 	if len(clusterName) == 0 {
 		clusterName = a.RegistrationAgent.AgentOptions.SpokeClusterName
 	}
 
-	if features.DefaultAgentMutableFeatureGate.Enabled(feature.ManagedClusterInfo) {
-		// start managed cluster info controller
-		klog.Info("starting managed cluster info addon agent")
-
-		if err := addons.StartManagedClusterInfoAgent(
-			ctx,
-			clusterName,
-			a.selfManagementEnabled,
-			hubKubeConfig,
-			spokeKubeConfig,
-			a.SpokeRestMapper,
-			a.SpokeKubeInformerFactory,
-			a.SpokeClusterInformerFactory,
-		); err != nil {
-			return err
-		}
+	agentContext := &addon.AgentContext{
+		Scheme:                      scheme,
+		ClusterName:                 clusterName,
+		SelfManagementEnabled:       a.selfManagementEnabled,
+		Config:                      a.AddOnsConfig,
+		HubKubeConfig:               hubKubeConfig,
+		HostingKubeConfig:           hostingKubeConfig,
+		SpokeKubeConfig:             spokeKubeConfig,
+		SpokeRestMapper:             a.SpokeRestMapper,
+		SpokeKubeInformerFactory:    a.SpokeKubeInformerFactory,
+		SpokeClusterInformerFactory: a.SpokeClusterInformerFactory,
+		AddHealthzCheck:             a.metricsServer.AddHealthzCheck,
+		// the agent accesses the managed cluster with the spoke kubeconfig file in the hosted mode
+		HostedMode: len(a.RegistrationAgent.AgentOptions.SpokeKubeconfigFile) != 0,
 	}
 
-	if features.DefaultAgentMutableFeatureGate.Enabled(feature.ConfigurationPolicy) {
-		klog.Info("starting configuration policy addon agent")
-		hubManager, err := a.newHubManager(hubKubeConfig, clusterName)
-		if err != nil {
-			return err
+	for _, agentAddOn := range addon.AgentAddOns() {
+		if !addon.Enabled(agentAddOn, features.DefaultAgentMutableFeatureGate) {
+			a.reportAddOnStatus(ctx, clusterName, agentAddOn.Name(), metav1.Condition{
+				Type:    addon.ConditionAvailable,
+				Status:  metav1.ConditionFalse,
+				Reason:  addon.ReasonAddOnDisabled,
+				Message: fmt.Sprintf("The feature gate %s is disabled", agentAddOn.FeatureGate()),
+			})
+			continue
 		}
 
-		hostingManager, err := a.newHostingManager(hostingKubeConfig, clusterName)
-		if err != nil {
-			return err
+		agentAddOn := agentAddOn
+		setup := func(ctx context.Context) error {
+			crdFS, spokeCRDFiles, hostingCRDFiles := agentAddOn.AgentCRDs()
+			if err := helpers.EnsureCRDs(ctx, scheme, spokeCRDClient, crdFS, spokeCRDFiles...); err != nil {
+				return err
+			}
+			if err := helpers.EnsureCRDs(ctx, scheme, hostingCRDClient, crdFS, hostingCRDFiles...); err != nil {
+				return err
+			}
+			return agentAddOn.SetupAgent(ctx, agentContext)
 		}
-
-		if err := addons.StartPolicyAgent(
-			ctx,
-			scheme,
-			clusterName,
-			hubKubeConfig,
-			hostingKubeConfig,
-			spokeKubeConfig,
-			hubManager,
-			hostingManager,
-			a.PolicyAgentConfig,
-		); err != nil {
-			klog.Fatalf("failed to setup policy addon, %v", err)
+		report := func(err error) {
+			a.reportAddOnSetup(ctx, clusterName, agentAddOn.Name(), err)
 		}
 
-		go func() {
-			klog.Info("starting the embedded hub controller-runtime manager in controlplane agent")
-			if err := hubManager.Start(ctx); err != nil {
-				klog.Fatalf("failed to start embedded hub controller-runtime manager, %v", err)
-			}
-		}()
-
-		go func() {
-			klog.Info("starting the embedded hosting controller-runtime manager in controlplane agent")
-			if err := hostingManager.Start(ctx); err != nil {
-				klog.Fatalf("failed to start embedded hosting controller-runtime manager, %v", err)
-			}
-		}()
+		klog.Infof("starting %s addon agent", agentAddOn.Name())
+		err := setup(ctx)
+		report(err)
+		if err != nil {
+			// the failed addon does not block the others, it is retried in the background
+			go addon.RetrySetup(ctx, setup, report)
+		}
 	}
 
+	a.addOnsStarted.Store(true)
 	return nil
 }
 
-func (a *AgentOptions) newHubManager(hubKubeConfig *rest.Config, clusterName string) (manager.Manager, error) {
-	mgr, err := ctrl.NewManager(hubKubeConfig, ctrl.Options{
-		Scheme:             scheme,
-		Namespace:          clusterName,
-		MetricsBindAddress: "0", //TODO think about the mertics later
-		Cache: cache.Options{
-			ByObject: map[client.Object]cache.ByObject{
-				&corev1.Secret{}: {
-					Field: fields.SelectorFromSet(fields.Set{"metadata.name": secretsync.SecretName}),
-				},
-				&policyv1.Policy{}: {
-					Field:     fields.SelectorFromSet(fields.Set{"metadata.namespace": clusterName}),
-					Transform: transformer,
-				},
-				&corev1.Event{}: {
-					Field: fields.SelectorFromSet(fields.Set{"metadata.namespace": clusterName}),
-				},
-			},
-			Namespaces: []string{clusterName},
-		},
-		// Override the EventBroadcaster so that the spam filter will not ignore events for the policy but with
-		// different messages if a large amount of events for that policy are sent in a short time.
-		EventBroadcaster: record.NewBroadcasterWithCorrelatorOptions(correlatorOptions),
-	})
+func (a *AgentOptions) reportAddOnSetup(ctx context.Context, clusterName, name string, err error) {
+	metrics.RecordAddOnAvailable(name, err == nil)
+	a.reportAddOnStatus(ctx, clusterName, name, addon.NewSetupCondition(err))
 	if err != nil {
-		return nil, err
+		klog.Errorf("failed to setup %s addon, will retry, %v", name, err)
+		a.failedAddOns.Store(name, err)
+		return
 	}
-
-	return mgr, nil
-}
-
-func (a *AgentOptions) newHostingManager(hostingKubeConfig *rest.Config, clusterName string) (manager.Manager, error) {
-	ctrlKey, err := configcommon.GetOperatorNamespacedName()
-	if err != nil {
-		return nil, err
-	}
-
-	mgr, err := ctrl.NewManager(hostingKubeConfig, ctrl.Options{
-		Scheme:             scheme,
-		MetricsBindAddress: "0", //TODO think about the mertics later
-		Cache: cache.Options{
-			ByObject: map[client.Object]cache.ByObject{
-				&apiextensionsv1.CustomResourceDefinition{}: {
-					Field: fields.SelectorFromSet(fields.Set{"metadata.name": controllers.CRDName}),
-				},
-				&appsv1.Deployment{}: {
-					Field: fields.SelectorFromSet(fields.Set{
-						"metadata.namespace": ctrlKey.Namespace,
-						"metadata.name":      ctrlKey.Name,
-					}),
-				},
-				&configpolicyv1.ConfigurationPolicy{}: {
-					Field:     fields.SelectorFromSet(fields.Set{"metadata.namespace": clusterName}),
-					Transform: transformer,
-				},
-				&policyv1.Policy{}: {
-					Field:     fields.SelectorFromSet(fields.Set{"metadata.namespace": clusterName}),
-					Transform: transformer,
-				},
-				&corev1.Event{}: {
-					Field: fields.SelectorFromSet(fields.Set{"metadata.namespace": clusterName}),
-				},
-			},
-			Namespaces: []string{ctrlKey.Namespace, clusterName},
-		},
-		ClientDisableCacheFor: []client.Object{&corev1.Secret{}},
-		// Override the EventBroadcaster so that the spam filter will not ignore events for the policy but with
-		// different messages if a large amount of events for that policy are sent in a short time.
-		EventBroadcaster: record.NewBroadcasterWithCorrelatorOptions(correlatorOptions),
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	return mgr, nil
+	a.failedAddOns.Delete(name)
 }
 
-// remove unused fields beforing pushing to cache to optimize memory usage
-func transformer(obj interface{}) (interface{}, error) {
-	k8sObj, ok := obj.(client.Object)
-	if !ok {
-		return nil, fmt.Errorf("invalid type")
+// reportAddOnStatus reports the setup status of the addon agent with the AddOnStatus on the cluster that the agent is
+// running on, e.g. kubectl get addonstatuses <cluster name>.<addon name>
+func (a *AgentOptions) reportAddOnStatus(ctx context.Context, clusterName, name string, cond metav1.Condition) {
+	statusName := addon.AgentStatusName(clusterName, name)
+	if err := addon.UpdateStatusCondition(ctx, a.hostingDynamicClient, statusName, cond); err != nil {
+		klog.Errorf("failed to update the status of %s addon, %v", name, err)
 	}
-	k8sObj.SetManagedFields(nil)
-	return k8sObj, nil
 }
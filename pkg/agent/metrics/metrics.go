@@ -0,0 +1,84 @@
+// Copyright Contributors to the Open Cluster Management project
+package metrics
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	startTime = time.Now()
+
+	// lastClusterInfoSync is the unix time of the last successful managed cluster info status sync
+	lastClusterInfoSync atomic.Int64
+
+	syncErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "multicluster_controlplane_agent_sync_errors_total",
+			Help: "The total number of the sync errors of the agent addon controllers.",
+		},
+		[]string{"controller"},
+	)
+
+	addOnAvailable = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "multicluster_controlplane_agent_addon_available",
+			Help: "Whether the agent addon is started (1) or failed to setup (0).",
+		},
+		[]string{"addon"},
+	)
+
+	clusterInfoLastSyncTime = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "multicluster_controlplane_agent_managedclusterinfo_last_sync_timestamp_seconds",
+			Help: "The unix time of the last successful status sync of the managed cluster info.",
+		},
+		func() float64 {
+			return float64(lastClusterInfoSync.Load())
+		},
+	)
+
+	clusterInfoStaleness = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "multicluster_controlplane_agent_managedclusterinfo_staleness_seconds",
+			Help: "The seconds since the last successful status sync of the managed cluster info, " +
+				"the seconds since the agent started are used if the status has not been synced yet.",
+		},
+		func() float64 {
+			last := lastClusterInfoSync.Load()
+			if last == 0 {
+				return time.Since(startTime).Seconds()
+			}
+			return time.Since(time.Unix(last, 0)).Seconds()
+		},
+	)
+)
+
+func init() {
+	// the agent controller-runtime managers share the controller-runtime registry, so the metrics of the agent
+	// addons are registered to it as well and exposed by one metrics endpoint.
+	ctrlmetrics.Registry.MustRegister(syncErrors, addOnAvailable, clusterInfoLastSyncTime, clusterInfoStaleness)
+}
+
+// RecordSyncError increases the sync errors of the given controller.
+func RecordSyncError(controller string) {
+	syncErrors.WithLabelValues(controller).Inc()
+}
+
+// RecordAddOnAvailable records whether the agent addon is started.
+func RecordAddOnAvailable(addOn string, available bool) {
+	if available {
+		addOnAvailable.WithLabelValues(addOn).Set(1)
+		return
+	}
+	addOnAvailable.WithLabelValues(addOn).Set(0)
+}
+
+// RecordClusterInfoSynced records the managed cluster info status is synced to the hub.
+func RecordClusterInfoSynced() {
+	lastClusterInfoSync.Store(time.Now().Unix())
+}
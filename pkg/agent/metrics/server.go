@@ -0,0 +1,144 @@
+// Copyright Contributors to the Open Cluster Management project
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const shutdownTimeout = 10 * time.Second
+
+// DebugFunc returns the object that is served by a debug endpoint as json.
+type DebugFunc func() (any, error)
+
+// Server serves the agent metrics, the liveness (/healthz) and the readiness (/readyz) probes, and the debug
+// endpoints (/debug/<name>).
+type Server struct {
+	lock          sync.RWMutex
+	healthzChecks map[string]healthz.Checker
+	readyzChecks  map[string]healthz.Checker
+	debugFuncs    map[string]DebugFunc
+}
+
+func NewServer() *Server {
+	return &Server{
+		healthzChecks: map[string]healthz.Checker{"ping": healthz.Ping},
+		readyzChecks:  map[string]healthz.Checker{},
+		debugFuncs:    map[string]DebugFunc{},
+	}
+}
+
+// AddHealthzCheck adds a liveness check to the server.
+func (s *Server) AddHealthzCheck(name string, check healthz.Checker) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.healthzChecks[name] = check
+}
+
+// AddReadyzCheck adds a readiness check to the server.
+func (s *Server) AddReadyzCheck(name string, check healthz.Checker) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.readyzChecks[name] = check
+}
+
+// AddDebugHandler adds a debug endpoint /debug/<name> to the server.
+func (s *Server) AddDebugHandler(name string, debugFunc DebugFunc) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.debugFuncs[name] = debugFunc
+}
+
+// Start serves the endpoints on the bind address until the context is done.
+func (s *Server) Start(ctx context.Context, bindAddress string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(ctrlmetrics.Registry, promhttp.HandlerOpts{
+		ErrorHandling: promhttp.HTTPErrorOnError,
+	}))
+	mux.Handle("/healthz", http.StripPrefix("/healthz", s.checksHandler(func() map[string]healthz.Checker {
+		return s.healthzChecks
+	})))
+	mux.Handle("/healthz/", http.StripPrefix("/healthz", s.checksHandler(func() map[string]healthz.Checker {
+		return s.healthzChecks
+	})))
+	mux.Handle("/readyz", http.StripPrefix("/readyz", s.checksHandler(func() map[string]healthz.Checker {
+		return s.readyzChecks
+	})))
+	mux.Handle("/readyz/", http.StripPrefix("/readyz", s.checksHandler(func() map[string]healthz.Checker {
+		return s.readyzChecks
+	})))
+	mux.Handle("/debug/", http.StripPrefix("/debug/", http.HandlerFunc(s.serveDebug)))
+
+	server := &http.Server{
+		Addr:              bindAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: 32 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			klog.Errorf("failed to shutdown the agent metrics server, %v", err)
+		}
+	}()
+
+	klog.Infof("serving the agent metrics and health probes on %s", bindAddress)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve the agent metrics and health probes, %v", err)
+	}
+	return nil
+}
+
+func (s *Server) checksHandler(checks func() map[string]healthz.Checker) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		s.lock.RLock()
+		handler := &healthz.Handler{Checks: map[string]healthz.Checker{}}
+		for name, check := range checks() {
+			handler.Checks[name] = check
+		}
+		s.lock.RUnlock()
+
+		handler.ServeHTTP(resp, req)
+	})
+}
+
+func (s *Server) serveDebug(resp http.ResponseWriter, req *http.Request) {
+	s.lock.RLock()
+	debugFunc, ok := s.debugFuncs[req.URL.Path]
+	s.lock.RUnlock()
+	if !ok {
+		http.NotFound(resp, req)
+		return
+	}
+
+	obj, err := debugFunc()
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp.Header().Set("Content-Type", "application/json")
+	if _, err := resp.Write(data); err != nil {
+		klog.Errorf("failed to write the debug response, %v", err)
+	}
+}
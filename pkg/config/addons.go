@@ -0,0 +1,215 @@
+// Copyright Contributors to the Open Cluster Management project
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// AddOnsConfigFileName is the name of the addons config file in the controlplane config dir.
+	AddOnsConfigFileName = "addons.yaml"
+
+	AddOnsConfigAPIVersion = "controlplane.open-cluster-management.io/v1alpha1"
+	AddOnsConfigKind       = "AddOnsConfig"
+)
+
+// AddOnsConfig holds the tunables of the controlplane addons.
+type AddOnsConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// ResyncPeriod is the resync period of the addon informers.
+	ResyncPeriod metav1.Duration `json:"resyncPeriod"`
+
+	// Policy is the config of the configuration policy addon.
+	Policy PolicyConfig `json:"policy"`
+
+	// Klusterlet is the config of the klusterlet controllers.
+	Klusterlet KlusterletConfig `json:"klusterlet"`
+
+	// ManagedClusterInfo is the config of the managed cluster info addon.
+	ManagedClusterInfo ManagedClusterInfoConfig `json:"managedClusterInfo"`
+}
+
+// ManagedClusterInfoConfig is the config of the managed cluster info addon.
+type ManagedClusterInfoConfig struct {
+	// ExtendedResources are the node resources, e.g. nvidia.com/gpu, hugepages-1Gi and ephemeral-storage, whose
+	// capacity of the worker and control plane nodes are aggregated into <resource>_worker and
+	// <resource>_controlplane of the managed cluster capacity.
+	ExtendedResources []string `json:"extendedResources"`
+}
+
+// KlusterletConfig is the config of the klusterlet controllers.
+type KlusterletConfig struct {
+	// ManagedResourcesEvictionGracePeriod is the default period to wait for an unreachable managed cluster when a
+	// klusterlet is deleted, the resources on the managed cluster are left behind after this period. It can be
+	// overridden by the operator.open-cluster-management.io/managed-resources-eviction-grace-period annotation of
+	// the klusterlet.
+	ManagedResourcesEvictionGracePeriod metav1.Duration `json:"managedResourcesEvictionGracePeriod"`
+	// ManagedClusterTokenTTL is the lifetime of the bound service account token that is used by the hosted agents to
+	// access their managed clusters, the token is refreshed before a fifth of its lifetime remains. The minimum is 10
+	// minutes.
+	ManagedClusterTokenTTL metav1.Duration `json:"managedClusterTokenTTL"`
+}
+
+// PolicyConfig is the config of the configuration policy addon.
+type PolicyConfig struct {
+	// ReportMetrics reports the GRC metrics from the policy propagator.
+	ReportMetrics bool `json:"reportMetrics"`
+
+	// KeyRotationDays is the days to rotate the policy encryption keys.
+	KeyRotationDays uint `json:"keyRotationDays"`
+
+	// EncryptionKeysConcurrentReconciles is the max concurrent reconciles of the policy encryption keys controller.
+	EncryptionKeysConcurrentReconciles uint `json:"encryptionKeysConcurrentReconciles"`
+
+	// RootPolicyStatusConcurrentReconciles is the max concurrent reconciles of the root policy status controller.
+	RootPolicyStatusConcurrentReconciles uint `json:"rootPolicyStatusConcurrentReconciles"`
+
+	// Agent is the config of the policy addon agent, it is used by the agent of the self management cluster.
+	Agent PolicyAgentConfig `json:"agent"`
+}
+
+// PolicyAgentConfig is the config of the configuration policy addon agent.
+type PolicyAgentConfig struct {
+	// DecryptionConcurrency is the max number of concurrent policy template decryptions.
+	DecryptionConcurrency uint8 `json:"decryptionConcurrency"`
+
+	// EvaluationConcurrency is the max number of concurrent configuration policy evaluations.
+	EvaluationConcurrency uint8 `json:"evaluationConcurrency"`
+
+	// EnableMetrics enables the custom metrics collection.
+	EnableMetrics bool `json:"enableMetrics"`
+
+	// Frequency is the status update frequency (in seconds) of a mutation policy.
+	Frequency uint `json:"frequency"`
+}
+
+// NewDefaultAddOnsConfig returns the addons config with the default values.
+func NewDefaultAddOnsConfig() *AddOnsConfig {
+	return &AddOnsConfig{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: AddOnsConfigAPIVersion,
+			Kind:       AddOnsConfigKind,
+		},
+		ResyncPeriod: metav1.Duration{Duration: 10 * time.Minute},
+		Policy: PolicyConfig{
+			ReportMetrics:                        true,
+			KeyRotationDays:                      30,
+			EncryptionKeysConcurrentReconciles:   10,
+			RootPolicyStatusConcurrentReconciles: 5,
+			Agent:                                *NewDefaultPolicyAgentConfig(),
+		},
+		Klusterlet: KlusterletConfig{
+			ManagedResourcesEvictionGracePeriod: metav1.Duration{Duration: 5 * time.Minute},
+			ManagedClusterTokenTTL:              metav1.Duration{Duration: time.Hour},
+		},
+		ManagedClusterInfo: ManagedClusterInfoConfig{
+			ExtendedResources: []string{"nvidia.com/gpu", "amd.com/gpu"},
+		},
+	}
+}
+
+// NewDefaultPolicyAgentConfig returns the policy addon agent config with the default values.
+func NewDefaultPolicyAgentConfig() *PolicyAgentConfig {
+	return &PolicyAgentConfig{
+		DecryptionConcurrency: 5,
+		// Set a low default to not add too much load to the Kubernetes API server in resource constrained deployments.
+		EvaluationConcurrency: 2,
+		Frequency:             10,
+	}
+}
+
+// LoadAddOnsConfig loads the addons config from the addons config file in the given config dir, the unset fields
+// are defaulted, and the default config is returned if the file does not exist.
+func LoadAddOnsConfig(configDir string) (*AddOnsConfig, error) {
+	c := NewDefaultAddOnsConfig()
+
+	configFile := path.Join(configDir, AddOnsConfigFileName)
+	data, err := os.ReadFile(configFile)
+	if os.IsNotExist(err) {
+		klog.Infof("The addons config file %s does not exist, using the default addons config", configFile)
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		return nil, fmt.Errorf("failed to load the addons config file %s, %v", configFile, err)
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid addons config file %s, %v", configFile, err)
+	}
+
+	return c, nil
+}
+
+// Validate validates the addons config.
+func (c *AddOnsConfig) Validate() error {
+	errs := []error{}
+
+	if c.APIVersion != AddOnsConfigAPIVersion {
+		errs = append(errs, fmt.Errorf("unsupported apiVersion %q, it should be %q", c.APIVersion, AddOnsConfigAPIVersion))
+	}
+	if c.Kind != AddOnsConfigKind {
+		errs = append(errs, fmt.Errorf("unsupported kind %q, it should be %q", c.Kind, AddOnsConfigKind))
+	}
+	if c.ResyncPeriod.Duration < time.Minute {
+		errs = append(errs, fmt.Errorf("resyncPeriod should not be less than 1m"))
+	}
+	if c.Policy.KeyRotationDays == 0 {
+		errs = append(errs, fmt.Errorf("policy.keyRotationDays should be greater than 0"))
+	}
+	if c.Policy.EncryptionKeysConcurrentReconciles == 0 {
+		errs = append(errs, fmt.Errorf("policy.encryptionKeysConcurrentReconciles should be greater than 0"))
+	}
+	if c.Policy.RootPolicyStatusConcurrentReconciles == 0 {
+		errs = append(errs, fmt.Errorf("policy.rootPolicyStatusConcurrentReconciles should be greater than 0"))
+	}
+	if err := c.Policy.Agent.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("invalid policy.agent, %v", err))
+	}
+	if c.Klusterlet.ManagedResourcesEvictionGracePeriod.Duration < 0 {
+		errs = append(errs, fmt.Errorf("klusterlet.managedResourcesEvictionGracePeriod should not be negative"))
+	}
+	if c.Klusterlet.ManagedClusterTokenTTL.Duration < 10*time.Minute {
+		errs = append(errs, fmt.Errorf("klusterlet.managedClusterTokenTTL should not be less than 10m"))
+	}
+	for _, name := range c.ManagedClusterInfo.ExtendedResources {
+		if msgs := validation.IsQualifiedName(name); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("managedClusterInfo.extendedResources %q is invalid, %s",
+				name, strings.Join(msgs, ",")))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// Validate validates the policy addon agent config.
+func (c *PolicyAgentConfig) Validate() error {
+	errs := []error{}
+
+	if c.DecryptionConcurrency == 0 {
+		errs = append(errs, fmt.Errorf("decryptionConcurrency should be greater than 0"))
+	}
+	if c.EvaluationConcurrency == 0 {
+		errs = append(errs, fmt.Errorf("evaluationConcurrency should be greater than 0"))
+	}
+	if c.Frequency == 0 {
+		errs = append(errs, fmt.Errorf("frequency should be greater than 0"))
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
@@ -5,11 +5,13 @@ import (
 
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
+	"github.com/stolostron/multicluster-controlplane/pkg/config"
 	"github.com/stolostron/multicluster-controlplane/pkg/controllers/addons/managedclusterinfo"
 )
 
-func SetupManagedClusterInfoWithManager(ctx context.Context, mgr manager.Manager) error {
-	if err := managedclusterinfo.SetupWithManager(mgr, ""); err != nil {
+func SetupManagedClusterInfoWithManager(ctx context.Context, mgr manager.Manager,
+	managedClusterInfoConfig config.ManagedClusterInfoConfig) error {
+	if err := managedclusterinfo.SetupWithManager(mgr, "", managedClusterInfoConfig.ExtendedResources); err != nil {
 		return err
 	}
 
@@ -4,13 +4,18 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 
 	clusterinfov1beta1 "github.com/stolostron/cluster-lifecycle-api/clusterinfo/v1beta1"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -22,6 +27,18 @@ import (
 	clusterclaims "github.com/stolostron/multicluster-controlplane/pkg/agent/addons/controllers/clusterclaim"
 )
 
+// DisableAutoDetectLabelsAnnotation disables the auto detection of the region, kubeVersion-major-minor, architecture
+// and controlPlaneTopology labels of a managed cluster, the value is a comma separated list of the labels, or "*" for
+// all of them.
+const DisableAutoDetectLabelsAnnotation = "open-cluster-management.io/disable-auto-detect-labels"
+
+// AutoDetectedLabelsAnnotation records the labels that are set by the auto detection, e.g. region=us-east-1, the
+// labels are updated by the auto detection until their values are changed by the users.
+const AutoDetectedLabelsAnnotation = "open-cluster-management.io/auto-detected-labels"
+
+// multiArchitecture is the architecture label value of the cluster whose nodes have different architectures
+const multiArchitecture = "multi"
+
 // AutoDetectReconciler auto detects platform related labels and sync to managedcluster
 type AutoDetectReconciler struct {
 	client client.Client
@@ -115,8 +132,56 @@ func (r *AutoDetectReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		}
 	}
 
+	// the labels are only set if they are absent, auto-detect or set by the auto detection, so the labels of the
+	// users are kept
+	disabledLabels := autoDetectDisabledLabels(cluster)
+	autoDetectedLabels := parseAutoDetectedLabels(cluster)
+	detectedLabels := detectLabels(cluster, clusterInfo)
+	newAutoDetectedLabels := map[string]string{}
+	for key, value := range autoDetectedLabels {
+		if disabledLabels.Has(key) || disabledLabels.Has("*") {
+			continue
+		}
+
+		// the value is unknown for now, e.g. the cluster is offline, the label is still owned by the auto detection
+		// if it is not changed, so it can be updated once the value is detected again
+		if _, ok := detectedLabels[key]; !ok && labels[key] == value {
+			newAutoDetectedLabels[key] = value
+		}
+	}
+	for key, value := range detectedLabels {
+		if disabledLabels.Has(key) || disabledLabels.Has("*") {
+			continue
+		}
+
+		current, ok := labels[key]
+		detected, autoDetected := autoDetectedLabels[key]
+		if ok && current != clusterinfov1beta1.AutoDetect && !(autoDetected && current == detected) {
+			continue
+		}
+
+		newAutoDetectedLabels[key] = value
+		if current != value {
+			labels[key] = value
+			needUpdate = true
+		}
+	}
+
+	annotations := cluster.GetAnnotations()
+	if !reflect.DeepEqual(autoDetectedLabels, newAutoDetectedLabels) {
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[AutoDetectedLabelsAnnotation] = formatAutoDetectedLabels(newAutoDetectedLabels)
+		if len(newAutoDetectedLabels) == 0 {
+			delete(annotations, AutoDetectedLabelsAnnotation)
+		}
+		needUpdate = true
+	}
+
 	if needUpdate {
 		cluster.SetLabels(labels)
+		cluster.SetAnnotations(annotations)
 		if err := r.client.Update(ctx, cluster); err != nil {
 			klog.Warningf("will reconcile since failed to add labels to ManagedCluster %v, %v", cluster.Name, err)
 			return reconcile.Result{}, err
@@ -138,6 +203,93 @@ func (r *AutoDetectReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	return ctrl.Result{}, nil
 }
 
+// autoDetectDisabledLabels returns the labels that are not auto detected for the cluster, "*" disables all of them.
+func autoDetectDisabledLabels(cluster *clusterv1.ManagedCluster) sets.Set[string] {
+	disabledLabels := sets.Set[string]{}
+	value, ok := cluster.Annotations[DisableAutoDetectLabelsAnnotation]
+	if !ok {
+		return disabledLabels
+	}
+
+	for _, key := range strings.Split(value, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			disabledLabels.Insert(key)
+		}
+	}
+	return disabledLabels
+}
+
+// parseAutoDetectedLabels returns the labels that are set by the auto detection from the AutoDetectedLabelsAnnotation.
+func parseAutoDetectedLabels(cluster *clusterv1.ManagedCluster) map[string]string {
+	autoDetectedLabels := map[string]string{}
+	for _, label := range strings.Split(cluster.Annotations[AutoDetectedLabelsAnnotation], ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(label), "=")
+		if ok && key != "" {
+			autoDetectedLabels[key] = value
+		}
+	}
+	return autoDetectedLabels
+}
+
+// formatAutoDetectedLabels returns the value of the AutoDetectedLabelsAnnotation, the labels are sorted by the keys.
+func formatAutoDetectedLabels(autoDetectedLabels map[string]string) string {
+	labels := []string{}
+	for key, value := range autoDetectedLabels {
+		labels = append(labels, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(labels)
+	return strings.Join(labels, ",")
+}
+
+// detectLabels returns the region, kubernetes minor version and control plane topology labels from the claims of the
+// cluster, and the architecture label from the nodes of the cluster. The labels are not returned if their values are
+// unknown, so the existing labels are kept.
+func detectLabels(cluster *clusterv1.ManagedCluster,
+	clusterInfo *clusterinfov1beta1.ManagedClusterInfo) map[string]string {
+	detectedLabels := map[string]string{}
+
+	for _, claim := range cluster.Status.ClusterClaims {
+		switch claim.Name {
+		case clusterclaims.ClaimOCMRegion:
+			detectedLabels[clusterclaims.LabelRegion] = claim.Value
+		case clusterclaims.ClaimControlPlaneTopology:
+			detectedLabels[clusterclaims.LabelControlPlaneTopology] = claim.Value
+		case clusterclaims.ClaimOCMKubeVersion:
+			kubeVersion, err := version.ParseGeneric(claim.Value)
+			if err != nil {
+				klog.V(4).Infof("failed to parse the kube version %q of the cluster %s, %v", claim.Value, cluster.Name, err)
+				continue
+			}
+			detectedLabels[clusterclaims.LabelKubeVersionMajorMinor] = fmt.Sprintf("%d.%d",
+				kubeVersion.Major(), kubeVersion.Minor())
+		}
+	}
+
+	// the architecture is multi if the nodes have different architectures
+	architectures := sets.Set[string]{}
+	for _, node := range clusterInfo.Status.NodeList {
+		if architecture := node.Labels[corev1.LabelArchStable]; architecture != "" {
+			architectures.Insert(architecture)
+		}
+	}
+	switch architectures.Len() {
+	case 0:
+	case 1:
+		detectedLabels[clusterclaims.LabelArchitecture] = architectures.UnsortedList()[0]
+	default:
+		detectedLabels[clusterclaims.LabelArchitecture] = multiArchitecture
+	}
+
+	for key, value := range detectedLabels {
+		if len(validation.IsValidLabelValue(value)) > 0 {
+			klog.V(4).Infof("skip the label %s of the cluster %s, the value %q is invalid", key, cluster.Name, value)
+			delete(detectedLabels, key)
+		}
+	}
+
+	return detectedLabels
+}
+
 // parseOCPVersion pasrses the full version of OCP and returns major, minor and patch.
 func parseOCPVersion(version string) (string, string, string) {
 	parts := strings.Split(version, ".")
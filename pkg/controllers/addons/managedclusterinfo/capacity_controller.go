@@ -15,28 +15,34 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/stolostron/multicluster-controlplane/pkg/helpers"
 )
 
 const (
-	resourceSocket               clusterv1.ResourceName = "socket"
-	resourceCoreWorker           clusterv1.ResourceName = "core_worker"
-	resourceSocketWorker         clusterv1.ResourceName = "socket_worker"
-	LabelNodeRoleOldControlPlane                        = "node-role.kubernetes.io/master"
-	LabelNodeRoleControlPlane                           = "node-role.kubernetes.io/control-plane"
-	LabelNodeRoleInfra                                  = "node-role.kubernetes.io/infra"
-	LabelNodeRoleWorker                                 = "node-role.kubernetes.io/worker"
+	resourceSocket       clusterv1.ResourceName = "socket"
+	resourceCoreWorker   clusterv1.ResourceName = "core_worker"
+	resourceSocketWorker clusterv1.ResourceName = "socket_worker"
 )
 
 type CapacityReconciler struct {
 	client client.Client
 	scheme *runtime.Scheme
+	// the worker and control plane capacity of the extendedResources are aggregated into the managed cluster capacity
+	extendedResources []clusterv1.ResourceName
 }
 
-// newAutoDetectReconciler returns a new reconcile.Reconciler
-func newCapacityReconciler(mgr manager.Manager) reconcile.Reconciler {
+// newCapacityReconciler returns a new reconcile.Reconciler
+func newCapacityReconciler(mgr manager.Manager, extendedResources []string) reconcile.Reconciler {
+	resourceNames := []clusterv1.ResourceName{}
+	for _, name := range extendedResources {
+		resourceNames = append(resourceNames, clusterv1.ResourceName(name))
+	}
+
 	return &CapacityReconciler{
-		client: mgr.GetClient(),
-		scheme: mgr.GetScheme(),
+		client:            mgr.GetClient(),
+		scheme:            mgr.GetScheme(),
+		extendedResources: resourceNames,
 	}
 }
 
@@ -91,6 +97,8 @@ func (r *CapacityReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	capacity[resourceSocketWorker] = socketWorkerCapacity
 	capacity[resourceCoreWorker] = coreWorkerCapacity
 
+	aggregateExtendedResources(nodes, r.extendedResources, capacity)
+
 	if apiequality.Semantic.DeepEqual(capacity, cluster.Status.Capacity) {
 		return ctrl.Result{}, nil
 	}
@@ -99,6 +107,59 @@ func (r *CapacityReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	return ctrl.Result{}, r.client.Status().Update(ctx, cluster)
 }
 
+// aggregateExtendedResources aggregates the extended resources of the worker nodes and the control plane nodes into
+// <resource>_worker and <resource>_controlplane of the capacity, the keys are removed if there is no such resource.
+// The total of a resource and the allocatable are reported by the registration agent, so they are not changed.
+func aggregateExtendedResources(nodes []clusterinfov1beta1.NodeStatus, extendedResources []clusterv1.ResourceName,
+	capacity clusterv1.ResourceList) {
+	for _, name := range extendedResources {
+		workerName := clusterv1.ResourceName(string(name) + "_worker")
+		controlPlaneName := clusterv1.ResourceName(string(name) + "_controlplane")
+
+		roleCapacity := clusterv1.ResourceList{}
+		for _, node := range nodes {
+			quantity, ok := node.Capacity[name]
+			if !ok {
+				continue
+			}
+
+			roleName := clusterv1.ResourceName("")
+			switch {
+			case isWorker(node):
+				roleName = workerName
+			case isControlPlane(node):
+				roleName = controlPlaneName
+			default:
+				continue
+			}
+
+			total := roleCapacity[roleName]
+			total.Add(quantity)
+			roleCapacity[roleName] = total
+		}
+
+		for _, roleName := range []clusterv1.ResourceName{workerName, controlPlaneName} {
+			quantity, ok := roleCapacity[roleName]
+			if !ok || quantity.IsZero() {
+				delete(capacity, roleName)
+				continue
+			}
+			capacity[roleName] = quantity
+		}
+	}
+}
+
+// isControlPlane returns true if the node has the control plane or master label and it is not a worker.
+func isControlPlane(node clusterinfov1beta1.NodeStatus) bool {
+	if isWorker(node) {
+		return false
+	}
+
+	_, isMaster := node.Labels[helpers.LabelNodeRoleOldControlPlane]
+	_, isControlPlane := node.Labels[helpers.LabelNodeRoleControlPlane]
+	return isMaster || isControlPlane
+}
+
 // for OCP,the master and infra nodes are not included in the subscription cost calculation.
 // the worker nodes are include the nodes with worker label or without controlPlane or infra label.
 func isWorker(node clusterinfov1beta1.NodeStatus) bool {
@@ -109,9 +170,9 @@ func isWorker(node clusterinfov1beta1.NodeStatus) bool {
 	isControlPlane := false
 	for key := range node.Labels {
 		switch key {
-		case LabelNodeRoleWorker:
+		case helpers.LabelNodeRoleWorker:
 			return true
-		case LabelNodeRoleOldControlPlane, LabelNodeRoleControlPlane, LabelNodeRoleInfra:
+		case helpers.LabelNodeRoleOldControlPlane, helpers.LabelNodeRoleControlPlane, helpers.LabelNodeRoleInfra:
 			isControlPlane = true
 		}
 	}
@@ -0,0 +1,97 @@
+package managedclusterinfo
+
+import (
+	"testing"
+
+	clusterinfov1beta1 "github.com/stolostron/cluster-lifecycle-api/clusterinfo/v1beta1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	"github.com/stolostron/multicluster-controlplane/pkg/helpers"
+)
+
+const resourceGPU clusterv1.ResourceName = "nvidia.com/gpu"
+
+func newNode(name, role string, capacity clusterinfov1beta1.ResourceList) clusterinfov1beta1.NodeStatus {
+	return clusterinfov1beta1.NodeStatus{
+		Name:     name,
+		Labels:   map[string]string{role: ""},
+		Capacity: capacity,
+	}
+}
+
+func TestAggregateExtendedResources(t *testing.T) {
+	cases := []struct {
+		name             string
+		nodes            []clusterinfov1beta1.NodeStatus
+		capacity         clusterv1.ResourceList
+		expectedCapacity clusterv1.ResourceList
+	}{
+		{
+			name: "no extended resources",
+			nodes: []clusterinfov1beta1.NodeStatus{
+				newNode("worker1", helpers.LabelNodeRoleWorker, clusterinfov1beta1.ResourceList{
+					clusterinfov1beta1.ResourceCPU: resource.MustParse("4"),
+				}),
+			},
+			capacity: clusterv1.ResourceList{
+				clusterv1.ResourceCPU: resource.MustParse("4"),
+			},
+			expectedCapacity: clusterv1.ResourceList{
+				clusterv1.ResourceCPU: resource.MustParse("4"),
+			},
+		},
+		{
+			name: "aggregate the worker and control plane resources",
+			nodes: []clusterinfov1beta1.NodeStatus{
+				newNode("master1", helpers.LabelNodeRoleControlPlane, clusterinfov1beta1.ResourceList{
+					resourceGPU: resource.MustParse("1"),
+				}),
+				newNode("worker1", helpers.LabelNodeRoleWorker, clusterinfov1beta1.ResourceList{
+					resourceGPU: resource.MustParse("2"),
+				}),
+				newNode("worker2", helpers.LabelNodeRoleWorker, clusterinfov1beta1.ResourceList{
+					resourceGPU: resource.MustParse("4"),
+				}),
+				newNode("infra1", helpers.LabelNodeRoleInfra, clusterinfov1beta1.ResourceList{
+					resourceGPU: resource.MustParse("8"),
+				}),
+			},
+			capacity: clusterv1.ResourceList{
+				resourceGPU: resource.MustParse("15"),
+			},
+			expectedCapacity: clusterv1.ResourceList{
+				resourceGPU:                   resource.MustParse("15"),
+				resourceGPU + "_worker":       resource.MustParse("6"),
+				resourceGPU + "_controlplane": resource.MustParse("1"),
+			},
+		},
+		{
+			name: "remove the resources that are gone",
+			nodes: []clusterinfov1beta1.NodeStatus{
+				newNode("worker1", helpers.LabelNodeRoleWorker, clusterinfov1beta1.ResourceList{
+					resourceGPU: resource.MustParse("2"),
+				}),
+			},
+			capacity: clusterv1.ResourceList{
+				resourceGPU:                   resource.MustParse("2"),
+				resourceGPU + "_worker":       resource.MustParse("2"),
+				resourceGPU + "_controlplane": resource.MustParse("1"),
+			},
+			expectedCapacity: clusterv1.ResourceList{
+				resourceGPU:             resource.MustParse("2"),
+				resourceGPU + "_worker": resource.MustParse("2"),
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			aggregateExtendedResources(c.nodes, []clusterv1.ResourceName{resourceGPU}, c.capacity)
+			if !apiequality.Semantic.DeepEqual(c.capacity, c.expectedCapacity) {
+				t.Errorf("expected capacity %v, but got %v", c.expectedCapacity, c.capacity)
+			}
+		})
+	}
+}
@@ -43,7 +43,7 @@ type ClusterInfoReconciler struct {
 	scheme *runtime.Scheme
 }
 
-func SetupWithManager(mgr manager.Manager, logCertSecret string) error {
+func SetupWithManager(mgr manager.Manager, logCertSecret string, extendedResources []string) error {
 	var err error
 
 	if len(logCertSecret) != 0 {
@@ -67,7 +67,7 @@ func SetupWithManager(mgr manager.Manager, logCertSecret string) error {
 	if err = add("clusterdetector-controller", mgr, newAutoDetectReconciler(mgr)); err != nil {
 		return err
 	}
-	if err = add("clustercapcity-controller", mgr, newCapacityReconciler(mgr)); err != nil {
+	if err = add("clustercapcity-controller", mgr, newCapacityReconciler(mgr, extendedResources)); err != nil {
 		return err
 	}
 	return nil
@@ -206,9 +206,12 @@ func (r *ClusterInfoReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		newClusterInfo.Status.NodeList = []clusterinfov1beta1.NodeStatus{}
 	}
 	newClusterInfo.Status.Conditions = clusterConditions
-	syncedCondition := meta.FindStatusCondition(clusterInfo.Status.Conditions, clusterinfov1beta1.ManagedClusterInfoSynced)
-	if syncedCondition != nil {
-		newClusterInfo.Status.Conditions = append(newClusterInfo.Status.Conditions, *syncedCondition)
+	// keep the conditions that are reported by the agent
+	for _, conditionType := range helpers.AgentClusterInfoConditions {
+		agentCondition := meta.FindStatusCondition(clusterInfo.Status.Conditions, conditionType)
+		if agentCondition != nil {
+			newClusterInfo.Status.Conditions = append(newClusterInfo.Status.Conditions, *agentCondition)
+		}
 	}
 
 	if !reflect.DeepEqual(newClusterInfo.Status, clusterInfo.Status) {
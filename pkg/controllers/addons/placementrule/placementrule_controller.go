@@ -0,0 +1,270 @@
+package placementrule
+
+import (
+	"context"
+	"reflect"
+	"sort"
+
+	clusterinfov1beta1 "github.com/stolostron/cluster-lifecycle-api/clusterinfo/v1beta1"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	placementrulev1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const controllerName = "placementrule-controller"
+
+// PlacementRuleReconciler computes the decisions of the PlacementRules that are scheduled by the default scheduler
+type PlacementRuleReconciler struct {
+	client client.Client
+	// clusterInfoEnabled indicates the ManagedClusterInfos are available to evaluate the resource hints
+	clusterInfoEnabled bool
+}
+
+// SetupWithManager adds the placementrule controller to the manager, the ManagedClusterInfos are watched and used
+// to evaluate the resource hints if clusterInfoEnabled is true.
+func SetupWithManager(mgr manager.Manager, clusterInfoEnabled bool) error {
+	r := &PlacementRuleReconciler{
+		client:             mgr.GetClient(),
+		clusterInfoEnabled: clusterInfoEnabled,
+	}
+
+	c, err := controller.New(controllerName, mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	if err := c.Watch(source.Kind(mgr.GetCache(), &placementrulev1.PlacementRule{}),
+		&handler.EnqueueRequestForObject{}); err != nil {
+		return err
+	}
+
+	// any change of the clusters may change the decisions of all placementrules
+	if err := c.Watch(source.Kind(mgr.GetCache(), &clusterv1.ManagedCluster{}),
+		handler.EnqueueRequestsFromMapFunc(r.allPlacementRules)); err != nil {
+		return err
+	}
+
+	if clusterInfoEnabled {
+		if err := c.Watch(source.Kind(mgr.GetCache(), &clusterinfov1beta1.ManagedClusterInfo{}),
+			handler.EnqueueRequestsFromMapFunc(r.allPlacementRules)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *PlacementRuleReconciler) allPlacementRules(ctx context.Context, _ client.Object) []reconcile.Request {
+	placementRules := &placementrulev1.PlacementRuleList{}
+	if err := r.client.List(ctx, placementRules); err != nil {
+		klog.Errorf("failed to list placementrules, %v", err)
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, placementRule := range placementRules.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: placementRule.Namespace, Name: placementRule.Name},
+		})
+	}
+	return requests
+}
+
+func (r *PlacementRuleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	placementRule := &placementrulev1.PlacementRule{}
+	if err := r.client.Get(ctx, req.NamespacedName, placementRule); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	if !placementRule.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	// the placementrule is scheduled by another scheduler
+	schedulerName := placementRule.Spec.SchedulerName
+	if schedulerName != "" &&
+		schedulerName != placementrulev1.SchedulerNameDefault &&
+		schedulerName != placementrulev1.SchedulerNameMCM {
+		return ctrl.Result{}, nil
+	}
+
+	decisions, err := r.decide(ctx, placementRule)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if reflect.DeepEqual(decisions, placementRule.Status.Decisions) {
+		return ctrl.Result{}, nil
+	}
+
+	klog.V(4).Infof("update the decisions of placementrule %s to %v", req.NamespacedName, decisions)
+	return ctrl.Result{}, retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		latest := &placementrulev1.PlacementRule{}
+		if err := r.client.Get(ctx, req.NamespacedName, latest); err != nil {
+			return err
+		}
+		latest.Status.Decisions = decisions
+		return r.client.Status().Update(ctx, latest)
+	})
+}
+
+// decide selects the clusters that match the clusters, clusterSelector and clusterConditions of the placementrule,
+// the selected clusters are sorted by the resource hint and then limited by the clusterReplicas.
+func (r *PlacementRuleReconciler) decide(
+	ctx context.Context, placementRule *placementrulev1.PlacementRule) ([]placementrulev1.PlacementDecision, error) {
+	clusters := &clusterv1.ManagedClusterList{}
+	if err := r.client.List(ctx, clusters); err != nil {
+		return nil, err
+	}
+
+	selector := labels.Everything()
+	if placementRule.Spec.ClusterSelector != nil {
+		var err error
+		selector, err = metav1.LabelSelectorAsSelector(placementRule.Spec.ClusterSelector)
+		if err != nil {
+			klog.Warningf("invalid cluster selector of placementrule %s/%s, %v",
+				placementRule.Namespace, placementRule.Name, err)
+			return nil, nil
+		}
+	}
+
+	names := map[string]bool{}
+	for _, cluster := range placementRule.Spec.Clusters {
+		names[cluster.Name] = true
+	}
+
+	selected := []clusterv1.ManagedCluster{}
+	for _, cluster := range clusters.Items {
+		if !cluster.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if len(names) > 0 && !names[cluster.Name] {
+			continue
+		}
+		if !selector.Matches(labels.Set(cluster.Labels)) {
+			continue
+		}
+		if !matchConditions(cluster.Status.Conditions, placementRule.Spec.ClusterConditions) {
+			continue
+		}
+		selected = append(selected, cluster)
+	}
+
+	hint := placementRule.Spec.ResourceHint
+	if hint != nil && hint.Type != placementrulev1.ResourceTypeNone {
+		resources := map[string]resource.Quantity{}
+		for _, cluster := range selected {
+			quantity, err := r.clusterResource(ctx, cluster, hint.Type)
+			if err != nil {
+				return nil, err
+			}
+			resources[cluster.Name] = quantity
+		}
+
+		sort.SliceStable(selected, func(i, j int) bool {
+			left, right := resources[selected[i].Name], resources[selected[j].Name]
+			if c := left.Cmp(right); c != 0 {
+				// the clusters with more resources are selected firstly by default
+				if hint.Order == placementrulev1.SelectionOrderAsce {
+					return c < 0
+				}
+				return c > 0
+			}
+			return selected[i].Name < selected[j].Name
+		})
+	} else {
+		sort.SliceStable(selected, func(i, j int) bool {
+			return selected[i].Name < selected[j].Name
+		})
+	}
+
+	if placementRule.Spec.ClusterReplicas != nil && int(*placementRule.Spec.ClusterReplicas) < len(selected) {
+		replicas := int(*placementRule.Spec.ClusterReplicas)
+		if replicas < 0 {
+			replicas = 0
+		}
+		selected = selected[:replicas]
+	}
+
+	decisions := []placementrulev1.PlacementDecision{}
+	for _, cluster := range selected {
+		decisions = append(decisions, placementrulev1.PlacementDecision{
+			ClusterName:      cluster.Name,
+			ClusterNamespace: cluster.Name,
+		})
+	}
+	if len(decisions) == 0 {
+		return nil, nil
+	}
+	return decisions, nil
+}
+
+// clusterResource returns the allocatable resource of the cluster, the capacity of the nodes in the
+// ManagedClusterInfo is used if the cluster does not report its allocatable resource.
+func (r *PlacementRuleReconciler) clusterResource(ctx context.Context,
+	cluster clusterv1.ManagedCluster, resourceType placementrulev1.ResourceType) (resource.Quantity, error) {
+	resourceName := clusterv1.ResourceName(resourceType)
+	if quantity, ok := cluster.Status.Allocatable[resourceName]; ok {
+		return quantity, nil
+	}
+
+	total := resource.Quantity{}
+	if !r.clusterInfoEnabled {
+		return total, nil
+	}
+
+	clusterInfo := &clusterinfov1beta1.ManagedClusterInfo{}
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: cluster.Name, Name: cluster.Name}, clusterInfo)
+	if errors.IsNotFound(err) {
+		return total, nil
+	}
+	if err != nil {
+		return total, err
+	}
+
+	for _, node := range clusterInfo.Status.NodeList {
+		if quantity, ok := node.Capacity[resourceName]; ok {
+			total.Add(quantity)
+		}
+	}
+	return total, nil
+}
+
+// matchConditions returns true if the cluster has all of the required conditions, the cluster is required to be
+// available if there are no required conditions.
+func matchConditions(conditions []metav1.Condition, filters []placementrulev1.ClusterConditionFilter) bool {
+	if len(filters) == 0 {
+		return meta.IsStatusConditionTrue(conditions, clusterv1.ManagedClusterConditionAvailable)
+	}
+
+	for _, filter := range filters {
+		cond := meta.FindStatusCondition(conditions, filter.Type)
+		if cond == nil {
+			return false
+		}
+		if filter.Status != "" && cond.Status != filter.Status {
+			return false
+		}
+	}
+	return true
+}
@@ -2,6 +2,7 @@ package addons
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"strings"
 	"sync"
@@ -26,11 +27,72 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/stolostron/multicluster-controlplane/pkg/addon"
+	"github.com/stolostron/multicluster-controlplane/pkg/config"
 )
 
-func SetupPolicyWithManager(ctx context.Context, mgr ctrl.Manager, kubeconfig *rest.Config,
-	kubeClient kubernetes.Interface, dynamicClient dynamic.Interface) error {
+// PolicySetupSteps returns the steps that set up the policy propagator controllers with the manager, the steps are
+// run with the addon.SetupSteps, so the setup can be retried after a failure without registering the controllers twice.
+func PolicySetupSteps(mgr ctrl.Manager, kubeconfig *rest.Config, kubeClient kubernetes.Interface,
+	dynamicClient dynamic.Interface, policyConfig config.PolicyConfig) []addon.SetupStep {
+	return []addon.SetupStep{
+		func(ctx context.Context) error {
+			propagatorctrl.Initialize(kubeconfig, &kubeClient)
+
+			// The following index for the PlacementRef Name is being added to the
+			// client cache to improve the performance of querying PlacementBindings
+			indexFunc := func(obj client.Object) []string {
+				return []string{obj.(*policyv1.PlacementBinding).PlacementRef.Name}
+			}
+
+			return mgr.GetCache().IndexField(ctx, &policyv1.PlacementBinding{}, "placementRef.name", indexFunc)
+		},
+		func(ctx context.Context) error {
+			return setupPropagatorWithManager(ctx, mgr, kubeconfig)
+		},
+		func(ctx context.Context) error {
+			if !reportMetrics(policyConfig) {
+				return nil
+			}
+
+			return (&metricsctrl.MetricReconciler{
+				Client: mgr.GetClient(),
+				Scheme: mgr.GetScheme(),
+			}).SetupWithManager(mgr)
+		},
+		func(ctx context.Context) error {
+			return (&automationctrl.PolicyAutomationReconciler{
+				Client:        mgr.GetClient(),
+				DynamicClient: dynamicClient,
+				Scheme:        mgr.GetScheme(),
+				Recorder:      mgr.GetEventRecorderFor(automationctrl.ControllerName),
+			}).SetupWithManager(mgr)
+		},
+		func(ctx context.Context) error {
+			return (&policysetctrl.PolicySetReconciler{
+				Client:   mgr.GetClient(),
+				Scheme:   mgr.GetScheme(),
+				Recorder: mgr.GetEventRecorderFor(policysetctrl.ControllerName),
+			}).SetupWithManager(mgr)
+		},
+		func(ctx context.Context) error {
+			return setupEncryptionKeysWithManager(mgr, policyConfig)
+		},
+		func(ctx context.Context) error {
+			return (&rootpolicystatusctrl.RootPolicyStatusReconciler{
+				Client:                  mgr.GetClient(),
+				MaxConcurrentReconciles: policyConfig.RootPolicyStatusConcurrentReconciles,
+				RootPolicyLocks:         &sync.Map{},
+				Scheme:                  mgr.GetScheme(),
+			}).SetupWithManager(mgr)
+		},
+	}
+}
 
+// setupPropagatorWithManager sets up the propagator controller, the dynamic watcher of the propagator is only started
+// after the controller is registered, so a failed setup does not leave a running watcher behind.
+func setupPropagatorWithManager(ctx context.Context, mgr ctrl.Manager, kubeconfig *rest.Config) error {
 	dynamicWatcherReconciler, _ := k8sdepwatches.NewControllerRuntimeSource()
 
 	dynamicWatcher, err := k8sdepwatches.New(kubeconfig, dynamicWatcherReconciler, nil)
@@ -38,14 +100,7 @@ func SetupPolicyWithManager(ctx context.Context, mgr ctrl.Manager, kubeconfig *r
 		return err
 	}
 
-	go func() {
-		err := dynamicWatcher.Start(ctx)
-		if err != nil {
-			klog.Error(err, "Unable to start the dynamic watcher", "controller", propagatorctrl.ControllerName)
-		}
-	}()
-
-	if err = (&propagatorctrl.PolicyReconciler{
+	if err := (&propagatorctrl.PolicyReconciler{
 		Client:          mgr.GetClient(),
 		Scheme:          mgr.GetScheme(),
 		Recorder:        mgr.GetEventRecorderFor(propagatorctrl.ControllerName),
@@ -55,107 +110,66 @@ func SetupPolicyWithManager(ctx context.Context, mgr ctrl.Manager, kubeconfig *r
 		return err
 	}
 
-	if reportMetrics() {
-		if err = (&metricsctrl.MetricReconciler{
-			Client: mgr.GetClient(),
-			Scheme: mgr.GetScheme(),
-		}).SetupWithManager(mgr); err != nil {
-			return err
+	watcherErr := make(chan error, 1)
+	go func() {
+		err := dynamicWatcher.Start(ctx)
+		if err != nil {
+			klog.Error(err, "Unable to start the dynamic watcher", "controller", propagatorctrl.ControllerName)
 		}
-	}
-
-	if err = (&automationctrl.PolicyAutomationReconciler{
-		Client:        mgr.GetClient(),
-		DynamicClient: dynamicClient,
-		Scheme:        mgr.GetScheme(),
-		Recorder:      mgr.GetEventRecorderFor(automationctrl.ControllerName),
-	}).SetupWithManager(mgr); err != nil {
-		return err
-	}
+		watcherErr <- err
+	}()
 
-	if err = (&policysetctrl.PolicySetReconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Recorder: mgr.GetEventRecorderFor(policysetctrl.ControllerName),
-	}).SetupWithManager(mgr); err != nil {
-		return err
+	klog.Info("Waiting for policy dynamic watcher to start")
+	// This is important to avoid adding watches before the dynamic watcher is ready
+	select {
+	case <-dynamicWatcher.Started():
+		return nil
+	case err := <-watcherErr:
+		return fmt.Errorf("the policy dynamic watcher is stopped, %v", err)
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
 
-	// TODO: allow KeyRotationDays & MaxConcurrentReconciles configuration
+func setupEncryptionKeysWithManager(mgr ctrl.Manager, policyConfig config.PolicyConfig) error {
 	encryptionkeysctrl := &encryptionkeysctrl.EncryptionKeysReconciler{
 		Client:                  mgr.GetClient(),
-		KeyRotationDays:         30,
-		MaxConcurrentReconciles: 10,
+		KeyRotationDays:         policyConfig.KeyRotationDays,
+		MaxConcurrentReconciles: policyConfig.EncryptionKeysConcurrentReconciles,
 		Scheme:                  mgr.GetScheme(),
 	}
 
-	// TODO: allow MaxConcurrentReconciles passed in as a parameter
-	if err = (&rootpolicystatusctrl.RootPolicyStatusReconciler{
-		Client:                  mgr.GetClient(),
-		MaxConcurrentReconciles: 5,
-		RootPolicyLocks:         &sync.Map{},
-		Scheme:                  mgr.GetScheme(),
-	}).SetupWithManager(mgr); err != nil {
-		return err
+	isEncryptionKeySecret := func(obj client.Object) bool {
+		return obj.GetName() == propagatorctrl.EncryptionKeySecret
 	}
 
 	// need to limit to
-	ctrl.NewControllerManagedBy(mgr).
+	return ctrl.NewControllerManagedBy(mgr).
 		// The work queue prevents the same item being reconciled concurrently:
 		// https://github.com/kubernetes-sigs/controller-runtime/issues/1416#issuecomment-899833144
 		WithOptions(controller.Options{MaxConcurrentReconciles: int(encryptionkeysctrl.MaxConcurrentReconciles)}).
 		Named("policy-encryption-keys").
 		For(&corev1.Secret{}, builder.WithPredicates(predicate.Funcs{
 			UpdateFunc: func(e event.UpdateEvent) bool {
-				if e.ObjectNew.GetName() == propagatorctrl.EncryptionKeySecret {
-					return true
-				} else {
-					return false
-				}
+				return isEncryptionKeySecret(e.ObjectNew)
 			},
 			CreateFunc: func(e event.CreateEvent) bool {
-				if e.Object.GetName() == propagatorctrl.EncryptionKeySecret {
-					return true
-				} else {
-					return false
-				}
+				return isEncryptionKeySecret(e.Object)
 			},
 			DeleteFunc: func(e event.DeleteEvent) bool {
-				if e.Object.GetName() == propagatorctrl.EncryptionKeySecret {
-					return true
-				} else {
-					return false
-				}
+				return isEncryptionKeySecret(e.Object)
 			},
 		})).
 		Complete(encryptionkeysctrl)
-
-	propagatorctrl.Initialize(kubeconfig, &kubeClient)
-
-	cache := mgr.GetCache()
-
-	// The following index for the PlacementRef Name is being added to the
-	// client cache to improve the performance of querying PlacementBindings
-	indexFunc := func(obj client.Object) []string {
-		return []string{obj.(*policyv1.PlacementBinding).PlacementRef.Name}
-	}
-
-	if err := cache.IndexField(
-		context.TODO(), &policyv1.PlacementBinding{}, "placementRef.name", indexFunc,
-	); err != nil {
-		panic(err)
-	}
-
-	klog.Info("Waiting for policy dynamic watcher to start")
-	// This is important to avoid adding watches before the dynamic watcher is ready
-	<-dynamicWatcher.Started()
-
-	return nil
 }
 
-// reportMetrics returns a bool on whether to report GRC metrics from the propagator
-func reportMetrics() bool {
+// reportMetrics returns a bool on whether to report GRC metrics from the propagator, the DISABLE_REPORT_METRICS
+// env is still respected for compatibility.
+func reportMetrics(policyConfig config.PolicyConfig) bool {
 	metrics, _ := os.LookupEnv("DISABLE_REPORT_METRICS")
+	if strings.EqualFold(metrics, "true") {
+		return false
+	}
 
-	return !strings.EqualFold(metrics, "true")
+	return policyConfig.ReportMetrics
 }
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/stolostron/multicluster-controlplane/pkg/config"
+)
+
+// addOnsConfigMapName is the name of the configmap that reflects the effective addons config in the controlplane
+const addOnsConfigMapName = "multicluster-controlplane-addons-config"
+
+func applyAddOnsConfigMap(ctx context.Context, kubeClient kubernetes.Interface, addOnsConfig *config.AddOnsConfig) error {
+	data, err := yaml.Marshal(addOnsConfig)
+	if err != nil {
+		return err
+	}
+
+	required := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      addOnsConfigMapName,
+			Namespace: metav1.NamespaceSystem,
+		},
+		Data: map[string]string{
+			config.AddOnsConfigFileName: string(data),
+		},
+	}
+
+	existing, err := kubeClient.CoreV1().ConfigMaps(metav1.NamespaceSystem).Get(ctx, addOnsConfigMapName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = kubeClient.CoreV1().ConfigMaps(metav1.NamespaceSystem).Create(ctx, required, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if existing.Data[config.AddOnsConfigFileName] == required.Data[config.AddOnsConfigFileName] {
+		return nil
+	}
+
+	existing = existing.DeepCopy()
+	existing.Data = required.Data
+	_, err = kubeClient.CoreV1().ConfigMaps(metav1.NamespaceSystem).Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
@@ -1,14 +1,19 @@
 package controllers
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"time"
 
 	clusterinfov1beta1 "github.com/stolostron/cluster-lifecycle-api/clusterinfo/v1beta1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
@@ -17,11 +22,13 @@ import (
 	"k8s.io/klog/v2"
 	aggregatorapiserver "k8s.io/kube-aggregator/pkg/apiserver"
 	clusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterinformer "open-cluster-management.io/api/client/cluster/informers/externalversions"
 	operatorclient "open-cluster-management.io/api/client/operator/clientset/versioned"
 	operatorinformer "open-cluster-management.io/api/client/operator/informers/externalversions"
 	workclient "open-cluster-management.io/api/client/work/clientset/versioned"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	operatorapiv1 "open-cluster-management.io/api/operator/v1"
 	policyv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
 	policyv1beta1 "open-cluster-management.io/governance-policy-propagator/api/v1beta1"
 	placementrulev1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
@@ -32,21 +39,28 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
-	"github.com/stolostron/multicluster-controlplane/pkg/controllers/addons"
+	"github.com/stolostron/multicluster-controlplane/pkg/addon"
+	_ "github.com/stolostron/multicluster-controlplane/pkg/addon/builtin"
+	"github.com/stolostron/multicluster-controlplane/pkg/config"
 	"github.com/stolostron/multicluster-controlplane/pkg/controllers/klusterlet"
+	"github.com/stolostron/multicluster-controlplane/pkg/controllers/klusterlet/controllers/autoimportcontroller"
 	"github.com/stolostron/multicluster-controlplane/pkg/controllers/manifests"
-	"github.com/stolostron/multicluster-controlplane/pkg/feature"
+	"github.com/stolostron/multicluster-controlplane/pkg/controllers/metrics"
 	"github.com/stolostron/multicluster-controlplane/pkg/helpers"
 )
 
 var requiredCRDs = []string{
-	"crds/apps.open-cluster-management.io_placementrules.crd.yaml",
-	"crds/internal.open-cluster-management.io_managedclusterinfos.crd.yaml",
-	"crds/policy.open-cluster-management.io_placementbindings.crd.yaml",
+	"crds/controlplane.open-cluster-management.io_addonstatuses.crd.yaml",
 	"crds/operator.open-cluster-management.io_klusterlets.crd.yaml",
-	"crds/policy.open-cluster-management.io_policies.crd.yaml",
-	"crds/policy.open-cluster-management.io_policyautomations.crd.yaml",
-	"crds/policy.open-cluster-management.io_policysets.crd.yaml",
+}
+
+// controllersBackoff is the backoff of restarting the controllers when they are failed to run.
+var controllersBackoff = wait.Backoff{
+	Duration: 5 * time.Second,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    math.MaxInt32,
+	Cap:      5 * time.Minute,
 }
 
 var scheme = runtime.NewScheme()
@@ -60,10 +74,18 @@ func init() {
 	utilruntime.Must(clusterinfov1beta1.AddToScheme(scheme))
 	utilruntime.Must(placementrulev1.AddToScheme(scheme))
 	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
+	utilruntime.Must(operatorapiv1.AddToScheme(scheme))
 }
 
 // InstallControllers installs next-gen controlplane controllers in hub cluster
-func InstallControllers(stopCh <-chan struct{}, aggregatorConfig *aggregatorapiserver.Config) error {
+func InstallControllers(addOnsConfig *config.AddOnsConfig) func(<-chan struct{}, *aggregatorapiserver.Config) error {
+	return func(stopCh <-chan struct{}, aggregatorConfig *aggregatorapiserver.Config) error {
+		return installControllers(stopCh, aggregatorConfig, addOnsConfig)
+	}
+}
+
+func installControllers(stopCh <-chan struct{}, aggregatorConfig *aggregatorapiserver.Config,
+	addOnsConfig *config.AddOnsConfig) error {
 	ctx := util.GoContext(stopCh)
 	loopbackRestConfig := aggregatorConfig.GenericConfig.LoopbackClientConfig
 	loopbackRestConfig.ContentType = "application/json"
@@ -91,104 +113,201 @@ func InstallControllers(stopCh <-chan struct{}, aggregatorConfig *aggregatorapis
 		return err
 	}
 
-	go func() {
-		mgr, err := ctrl.NewManager(loopbackRestConfig, ctrl.Options{
-			Scheme:             scheme,
-			MetricsBindAddress: "0", //TODO think about the mertics later
-			Cache: cache.Options{
-				ByObject: map[client.Object]cache.ByObject{
-					&policyv1.Policy{}: {
-						Transform: func(obj interface{}) (interface{}, error) {
-							k8sObj, ok := obj.(client.Object)
-							if !ok {
-								return nil, fmt.Errorf("invalid type")
-							}
-							k8sObj.SetManagedFields(nil)
-							return k8sObj, nil
-						},
+	// the controllers are only run on the leader replica
+	return runWithLeaderElection(ctx, controlplaneKubeClient, func(ctx context.Context) {
+		backoff := controllersBackoff
+		for {
+			err := runControllers(ctx, addOnsConfig,
+				loopbackRestConfig, controlplaneKubeClient, controlplaneDynamicClient, controlplaneCRDClient)
+			if err == nil || ctx.Err() != nil {
+				return
+			}
+
+			delay := backoff.Step()
+			klog.Errorf("failed to run controllers, retry after %s, %v", delay, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+		}
+	})
+}
+
+func runControllers(ctx context.Context,
+	addOnsConfig *config.AddOnsConfig,
+	loopbackRestConfig *rest.Config,
+	controlplaneKubeClient kubernetes.Interface,
+	controlplaneDynamicClient dynamic.Interface,
+	controlplaneCRDClient apiextensionsclient.Interface) error {
+	// stop the addon setup retries of this run once the manager is stopped
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	mgr, err := ctrl.NewManager(loopbackRestConfig, ctrl.Options{
+		Scheme: scheme,
+		// the metrics of the manager are exposed by the controlplane apiserver /metrics endpoint
+		MetricsBindAddress: "0",
+		Cache: cache.Options{
+			ByObject: map[client.Object]cache.ByObject{
+				&policyv1.Policy{}: {
+					Transform: func(obj interface{}) (interface{}, error) {
+						k8sObj, ok := obj.(client.Object)
+						if !ok {
+							return nil, fmt.Errorf("invalid type")
+						}
+						k8sObj.SetManagedFields(nil)
+						return k8sObj, nil
 					},
 				},
 			},
-		})
-		if err != nil {
-			klog.Fatalf("unable to start manager %v", err)
-		}
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to create manager %v", err)
+	}
 
-		if features.DefaultControlplaneMutableFeatureGate.Enabled(feature.ManagedClusterInfo) {
-			klog.Info("starting managed cluster info addon")
-			if err := addons.SetupManagedClusterInfoWithManager(ctx, mgr); err != nil {
-				klog.Fatalf("failed to setup managedclusterinfo controller %v", err)
-			}
-		}
+	metrics.Register()
+	if err := metrics.RegisterControlplaneCollector(mgr.GetClient()); err != nil {
+		return fmt.Errorf("failed to register controlplane metrics %v", err)
+	}
+	// the controlplane metrics are only reported by the leader
+	defer metrics.UnregisterControlplaneCollector()
 
-		if features.DefaultControlplaneMutableFeatureGate.Enabled(feature.ConfigurationPolicy) {
-			klog.Info("starting policy addon")
-			if err := addons.SetupPolicyWithManager(
-				ctx,
-				mgr,
-				loopbackRestConfig,
-				controlplaneKubeClient,
-				controlplaneDynamicClient,
-			); err != nil {
-				klog.Fatalf("failed to setup policy controller %v", err)
-			}
+	// reflect the effective addons config back to the controlplane
+	if err := applyAddOnsConfigMap(ctx, controlplaneKubeClient, addOnsConfig); err != nil {
+		klog.Errorf("failed to apply the addons config configmap, %v", err)
+	}
+
+	for _, hubAddOn := range addon.HubAddOns() {
+		if !addon.Enabled(hubAddOn, features.DefaultControlplaneMutableFeatureGate) {
+			reportHubAddOnStatus(ctx, controlplaneDynamicClient, hubAddOn, metav1.Condition{
+				Type:    addon.ConditionAvailable,
+				Status:  metav1.ConditionFalse,
+				Reason:  addon.ReasonAddOnDisabled,
+				Message: fmt.Sprintf("The feature gate %s is disabled", hubAddOn.FeatureGate()),
+			})
+			continue
 		}
 
-		if restConfig, err := rest.InClusterConfig(); err == nil {
-			controlplaneOperatorClient, err := operatorclient.NewForConfig(loopbackRestConfig)
-			if err != nil {
-				klog.Fatalf("failed to build controlplane operator client %v", err)
+		hubAddOn := hubAddOn
+		hubContext := &addon.HubContext{
+			Config:        addOnsConfig,
+			Manager:       mgr,
+			KubeConfig:    loopbackRestConfig,
+			KubeClient:    controlplaneKubeClient,
+			DynamicClient: controlplaneDynamicClient,
+			SetupSteps:    &addon.SetupSteps{},
+		}
+		setup := func(ctx context.Context) error {
+			crdFS, crdFiles := hubAddOn.HubCRDs()
+			if err := helpers.EnsureCRDs(ctx, scheme, controlplaneCRDClient, crdFS, crdFiles...); err != nil {
+				return err
 			}
+			return hubAddOn.SetupHub(ctx, hubContext)
+		}
+		report := func(err error) {
+			reportHubAddOnSetup(ctx, controlplaneDynamicClient, hubAddOn, err)
+		}
 
-			controlplaneClusterClient, err := clusterclient.NewForConfig(loopbackRestConfig)
-			if err != nil {
-				klog.Fatalf("failed to build controlplane cluster client %v", err)
-			}
+		klog.Infof("starting %s addon", hubAddOn.Name())
+		err := setup(ctx)
+		report(err)
+		if err != nil {
+			// the failed addon does not block the others, it is retried with the manager after the manager is
+			// started, the succeeded setup steps of the addon are not run again.
+			go addon.RetrySetup(ctx, setup, report)
+		}
+	}
 
-			controlplaneWorkClient, err := workclient.NewForConfig(loopbackRestConfig)
-			if err != nil {
-				klog.Fatalf("failed to build controlplane work client %v", err)
-			}
+	if restConfig, err := rest.InClusterConfig(); err == nil {
+		controlplaneOperatorClient, err := operatorclient.NewForConfig(loopbackRestConfig)
+		if err != nil {
+			return fmt.Errorf("failed to build controlplane operator client %v", err)
+		}
 
-			kubeClient, err := kubernetes.NewForConfig(restConfig)
-			if err != nil {
-				klog.Fatalf("failed to build kube client on the management cluster %v", err)
-			}
+		controlplaneClusterClient, err := clusterclient.NewForConfig(loopbackRestConfig)
+		if err != nil {
+			return fmt.Errorf("failed to build controlplane cluster client %v", err)
+		}
 
-			workClient, err := workclient.NewForConfig(restConfig)
-			if err != nil {
-				klog.Fatalf("failed to build work client on the management cluster %v", err)
-			}
+		controlplaneWorkClient, err := workclient.NewForConfig(loopbackRestConfig)
+		if err != nil {
+			return fmt.Errorf("failed to build controlplane work client %v", err)
+		}
 
-			kubeInformerFactory := informers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
-			operatorInformerFactory := operatorinformer.NewSharedInformerFactory(controlplaneOperatorClient, 10*time.Minute)
-
-			klog.Info("starting klusterlet")
-			klusterlet := klusterlet.NewKlusterlet(
-				controlplaneKubeClient,
-				controlplaneDynamicClient,
-				controlplaneClusterClient,
-				controlplaneWorkClient,
-				controlplaneCRDClient,
-				controlplaneOperatorClient.OperatorV1().Klusterlets(),
-				kubeClient,
-				workClient.WorkV1().AppliedManifestWorks(),
-				kubeInformerFactory,
-				operatorInformerFactory.Operator().V1().Klusterlets(),
-			)
-
-			go kubeInformerFactory.Start(ctx.Done())
-			go operatorInformerFactory.Start(ctx.Done())
-
-			klusterlet.Start(ctx)
+		kubeClient, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			return fmt.Errorf("failed to build kube client on the management cluster %v", err)
 		}
 
-		if err := mgr.Start(ctx); err != nil {
-			klog.Fatalf("failed to start controller manager, %v", err)
+		workClient, err := workclient.NewForConfig(restConfig)
+		if err != nil {
+			return fmt.Errorf("failed to build work client on the management cluster %v", err)
 		}
 
-		<-ctx.Done()
-	}()
+		kubeInformerFactory := informers.NewSharedInformerFactory(kubeClient, addOnsConfig.ResyncPeriod.Duration)
+		operatorInformerFactory := operatorinformer.NewSharedInformerFactory(
+			controlplaneOperatorClient, addOnsConfig.ResyncPeriod.Duration)
+		// only the auto import secrets in the managed cluster namespaces are watched on the controlplane
+		controlplaneKubeInformerFactory := informers.NewSharedInformerFactoryWithOptions(
+			controlplaneKubeClient,
+			addOnsConfig.ResyncPeriod.Duration,
+			informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+				options.FieldSelector = fields.OneTermEqualSelector(
+					"metadata.name", autoimportcontroller.AutoImportSecretName).String()
+			}),
+		)
 
+		controlplaneClusterInformerFactory := clusterinformer.NewSharedInformerFactory(
+			controlplaneClusterClient, addOnsConfig.ResyncPeriod.Duration)
+		// the RBAC changes on the controlplane invalidate the cached access review results of the klusterlets
+		controlplaneRBACInformerFactory := informers.NewSharedInformerFactory(
+			controlplaneKubeClient, addOnsConfig.ResyncPeriod.Duration)
+
+		klog.Info("starting klusterlet")
+		klusterlet := klusterlet.NewKlusterlet(
+			controlplaneKubeClient,
+			controlplaneDynamicClient,
+			controlplaneClusterClient,
+			controlplaneWorkClient,
+			controlplaneCRDClient,
+			controlplaneOperatorClient.OperatorV1().Klusterlets(),
+			kubeClient,
+			workClient.WorkV1().AppliedManifestWorks(),
+			kubeInformerFactory,
+			operatorInformerFactory.Operator().V1().Klusterlets(),
+			controlplaneKubeInformerFactory.Core().V1().Secrets(),
+			controlplaneClusterInformerFactory.Cluster().V1().ManagedClusters(),
+			controlplaneRBACInformerFactory.Rbac().V1(),
+			addOnsConfig.Klusterlet.ManagedResourcesEvictionGracePeriod.Duration,
+			addOnsConfig.Klusterlet.ManagedClusterTokenTTL.Duration,
+		)
+
+		go kubeInformerFactory.Start(ctx.Done())
+		go operatorInformerFactory.Start(ctx.Done())
+		go controlplaneKubeInformerFactory.Start(ctx.Done())
+		go controlplaneClusterInformerFactory.Start(ctx.Done())
+		go controlplaneRBACInformerFactory.Start(ctx.Done())
+
+		klusterlet.Start(ctx)
+	}
+
+	if err := mgr.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start controller manager, %v", err)
+	}
 	return nil
 }
+
+func reportHubAddOnSetup(ctx context.Context, client dynamic.Interface, hubAddOn addon.HubAddOn, err error) {
+	if err != nil {
+		klog.Errorf("failed to setup %s addon, %v", hubAddOn.Name(), err)
+	}
+	reportHubAddOnStatus(ctx, client, hubAddOn, addon.NewSetupCondition(err))
+}
+
+func reportHubAddOnStatus(ctx context.Context, client dynamic.Interface, hubAddOn addon.HubAddOn, cond metav1.Condition) {
+	if err := addon.UpdateStatusCondition(ctx, client, hubAddOn.Name(), cond); err != nil {
+		klog.Errorf("failed to update the status of %s addon, %v", hubAddOn.Name(), err)
+	}
+}
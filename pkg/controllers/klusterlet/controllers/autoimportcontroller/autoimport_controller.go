@@ -0,0 +1,307 @@
+// Copyright Contributors to the Open Cluster Management project
+package autoimportcontroller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	coreinformer "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corelister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	clusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	operatorv1client "open-cluster-management.io/api/client/operator/clientset/versioned/typed/operator/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	operatorapiv1 "open-cluster-management.io/api/operator/v1"
+
+	"github.com/stolostron/multicluster-controlplane/pkg/controllers/klusterlet/helpers"
+)
+
+const (
+	// AutoImportSecretName is the secret name of the auto import secret, it is created by user in the managed cluster
+	// namespace on the controlplane, it holds a kubeconfig (kubeconfig), or a server url (server) and a token (token)
+	// of the managed cluster, the ca (ca.crt) of the server is optional.
+	AutoImportSecretName = "auto-import-secret"
+
+	// ConditionManagedClusterImportSucceeded is the condition type of the managed cluster that reports the progress of
+	// the auto import.
+	ConditionManagedClusterImportSucceeded = "ManagedClusterImportSucceeded"
+
+	ReasonManagedClusterImporting    = "ManagedClusterImporting"
+	ReasonManagedClusterImported     = "ManagedClusterImported"
+	ReasonManagedClusterImportFailed = "ManagedClusterImportFailed"
+
+	kubeconfigKey = "kubeconfig"
+	serverKey     = "server"
+	tokenKey      = "token"
+	caKey         = "ca.crt"
+)
+
+// AutoImportRequeueInterval is exposed so that integration tests can crank up the controller sync speed.
+var AutoImportRequeueInterval = 10 * time.Second
+
+// autoImportController imports the managed clusters in the hosted mode with the auto import secrets, it creates the
+// managed cluster and the klusterlet, and provides the managed cluster kubeconfig for the klusterlet, the auto import
+// secret is deleted after the managed cluster is joined.
+type autoImportController struct {
+	controlplaneKubeClient    kubernetes.Interface
+	controlplaneClusterClient clusterclient.Interface
+	klusterletClient          operatorv1client.KlusterletInterface
+	secretLister              corelister.SecretLister
+	recorder                  events.Recorder
+}
+
+// NewAutoImportController returns an autoImportController, the secret informer is for the auto import secrets on
+// the controlplane.
+func NewAutoImportController(
+	controlplaneKubeClient kubernetes.Interface,
+	controlplaneClusterClient clusterclient.Interface,
+	klusterletClient operatorv1client.KlusterletInterface,
+	secretInformer coreinformer.SecretInformer,
+	recorder events.Recorder) factory.Controller {
+	controller := &autoImportController{
+		controlplaneKubeClient:    controlplaneKubeClient,
+		controlplaneClusterClient: controlplaneClusterClient,
+		klusterletClient:          klusterletClient,
+		secretLister:              secretInformer.Lister(),
+		recorder:                  recorder,
+	}
+	return factory.New().WithSync(controller.sync).
+		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			if accessor.GetName() != AutoImportSecretName {
+				return ""
+			}
+			return accessor.GetNamespace()
+		}, secretInformer.Informer()).
+		ToController("AutoImportController", recorder)
+}
+
+func (c *autoImportController) sync(ctx context.Context, controllerContext factory.SyncContext) error {
+	clusterName := controllerContext.QueueKey()
+	if clusterName == "" || clusterName == factory.DefaultQueueKey {
+		return nil
+	}
+
+	klog.V(4).Infof("Reconciling auto import secret of managed cluster %q", clusterName)
+
+	autoImportSecret, err := c.secretLister.Secrets(clusterName).Get(AutoImportSecretName)
+	switch {
+	case errors.IsNotFound(err):
+		return nil
+	case err != nil:
+		return err
+	}
+
+	if err := c.ensureManagedCluster(ctx, clusterName); err != nil {
+		return err
+	}
+
+	kubeconfigData, err := buildKubeConfig(autoImportSecret)
+	if err == nil {
+		err = validateConnectivity(kubeconfigData)
+	}
+	if err != nil {
+		// the import is retried once the auto import secret is changed
+		return c.updateImportCondition(ctx, clusterName, metav1.ConditionFalse, ReasonManagedClusterImportFailed,
+			fmt.Sprintf("Failed to connect to the managed cluster with the auto import secret, %v", err))
+	}
+
+	if err := c.applyManagedClusterKubeConfig(ctx, clusterName, kubeconfigData); err != nil {
+		return err
+	}
+
+	if err := c.ensureKlusterlet(ctx, clusterName); err != nil {
+		return err
+	}
+
+	managedCluster, err := c.controlplaneClusterClient.ClusterV1().ManagedClusters().Get(
+		ctx, clusterName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	if !meta.IsStatusConditionTrue(managedCluster.Status.Conditions, clusterv1.ManagedClusterConditionJoined) {
+		controllerContext.Queue().AddAfter(clusterName, AutoImportRequeueInterval)
+		return c.updateImportCondition(ctx, clusterName, metav1.ConditionFalse, ReasonManagedClusterImporting,
+			"Waiting for the managed cluster to join")
+	}
+
+	if err := c.updateImportCondition(ctx, clusterName, metav1.ConditionTrue, ReasonManagedClusterImported,
+		"The managed cluster is imported"); err != nil {
+		return err
+	}
+
+	// the credential is not required after the managed cluster is imported
+	err = c.controlplaneKubeClient.CoreV1().Secrets(clusterName).Delete(ctx, AutoImportSecretName, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	c.recorder.Eventf("AutoImportSecretDeleted", "The managed cluster %s is imported, the auto import secret is deleted",
+		clusterName)
+	return nil
+}
+
+func (c *autoImportController) ensureManagedCluster(ctx context.Context, clusterName string) error {
+	_, err := c.controlplaneClusterClient.ClusterV1().ManagedClusters().Get(ctx, clusterName, metav1.GetOptions{})
+	if err == nil || !errors.IsNotFound(err) {
+		return err
+	}
+
+	_, err = c.controlplaneClusterClient.ClusterV1().ManagedClusters().Create(ctx, &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: clusterName},
+		Spec:       clusterv1.ManagedClusterSpec{HubAcceptsClient: true},
+	}, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	c.recorder.Eventf("ManagedClusterCreated", "The managed cluster %s is created for auto import", clusterName)
+	return nil
+}
+
+func (c *autoImportController) ensureKlusterlet(ctx context.Context, clusterName string) error {
+	klusterlet, err := c.klusterletClient.Get(ctx, clusterName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		_, err = c.klusterletClient.Create(ctx, &operatorapiv1.Klusterlet{
+			ObjectMeta: metav1.ObjectMeta{Name: clusterName},
+			Spec: operatorapiv1.KlusterletSpec{
+				DeployOption: operatorapiv1.KlusterletDeployOption{Mode: operatorapiv1.InstallModeHosted},
+			},
+		}, metav1.CreateOptions{})
+		if err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
+		c.recorder.Eventf("KlusterletCreated", "The klusterlet %s is created for auto import", clusterName)
+		return nil
+	case err != nil:
+		return err
+	}
+
+	if klusterlet.Spec.DeployOption.Mode != operatorapiv1.InstallModeHosted {
+		return fmt.Errorf("the klusterlet %s already exists and it is not in the hosted mode", clusterName)
+	}
+	return nil
+}
+
+// applyManagedClusterKubeConfig saves the kubeconfig of the managed cluster, the klusterlet uses it to install
+// resources on the managed cluster.
+func (c *autoImportController) applyManagedClusterKubeConfig(
+	ctx context.Context, clusterName string, kubeconfigData []byte) error {
+	secret, err := c.controlplaneKubeClient.CoreV1().Secrets(clusterName).Get(
+		ctx, helpers.ManagedClusterKubeConfig, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		_, err = c.controlplaneKubeClient.CoreV1().Secrets(clusterName).Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      helpers.ManagedClusterKubeConfig,
+				Namespace: clusterName,
+			},
+			Data: map[string][]byte{kubeconfigKey: kubeconfigData},
+		}, metav1.CreateOptions{})
+		return err
+	case err != nil:
+		return err
+	}
+
+	if string(secret.Data[kubeconfigKey]) == string(kubeconfigData) {
+		return nil
+	}
+
+	secret = secret.DeepCopy()
+	secret.Data = map[string][]byte{kubeconfigKey: kubeconfigData}
+	_, err = c.controlplaneKubeClient.CoreV1().Secrets(clusterName).Update(ctx, secret, metav1.UpdateOptions{})
+	return err
+}
+
+func (c *autoImportController) updateImportCondition(ctx context.Context, clusterName string,
+	status metav1.ConditionStatus, reason, message string) error {
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		managedCluster, err := c.controlplaneClusterClient.ClusterV1().ManagedClusters().Get(
+			ctx, clusterName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		cond := meta.FindStatusCondition(managedCluster.Status.Conditions, ConditionManagedClusterImportSucceeded)
+		if cond != nil && cond.Status == status && cond.Reason == reason && cond.Message == message {
+			return nil
+		}
+
+		managedCluster = managedCluster.DeepCopy()
+		meta.SetStatusCondition(&managedCluster.Status.Conditions, metav1.Condition{
+			Type:    ConditionManagedClusterImportSucceeded,
+			Status:  status,
+			Reason:  reason,
+			Message: message,
+		})
+		_, err = c.controlplaneClusterClient.ClusterV1().ManagedClusters().UpdateStatus(
+			ctx, managedCluster, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// buildKubeConfig returns the kubeconfig of the managed cluster from the auto import secret, the kubeconfig in the
+// secret is preferred to the server and token.
+func buildKubeConfig(secret *corev1.Secret) ([]byte, error) {
+	if kubeconfigData, ok := secret.Data[kubeconfigKey]; ok {
+		config, err := clientcmd.Load(kubeconfigData)
+		if err != nil {
+			return nil, fmt.Errorf("invalid kubeconfig, %v", err)
+		}
+		// flatten the kubeconfig to make sure it does not reference any local files
+		if err := clientcmdapi.FlattenConfig(config); err != nil {
+			return nil, fmt.Errorf("invalid kubeconfig, %v", err)
+		}
+		return clientcmd.Write(*config)
+	}
+
+	server, token := secret.Data[serverKey], secret.Data[tokenKey]
+	if len(server) == 0 || len(token) == 0 {
+		return nil, fmt.Errorf("the auto import secret requires a %s, or a %s and a %s",
+			kubeconfigKey, serverKey, tokenKey)
+	}
+
+	cluster := &clientcmdapi.Cluster{Server: string(server)}
+	if ca, ok := secret.Data[caKey]; ok && len(ca) > 0 {
+		cluster.CertificateAuthorityData = ca
+	} else {
+		cluster.InsecureSkipTLSVerify = true
+	}
+
+	return clientcmd.Write(clientcmdapi.Config{
+		Clusters:       map[string]*clientcmdapi.Cluster{"cluster": cluster},
+		AuthInfos:      map[string]*clientcmdapi.AuthInfo{"user": {Token: string(token)}},
+		Contexts:       map[string]*clientcmdapi.Context{"default": {Cluster: "cluster", AuthInfo: "user"}},
+		CurrentContext: "default",
+	})
+}
+
+func validateConnectivity(kubeconfigData []byte) error {
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfigData)
+	if err != nil {
+		return err
+	}
+	config.Timeout = 10 * time.Second
+
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	_, err = kubeClient.Discovery().ServerVersion()
+	return err
+}
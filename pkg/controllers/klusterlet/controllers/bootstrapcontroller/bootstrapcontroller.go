@@ -3,7 +3,9 @@ package bootstrapcontroller
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
@@ -22,15 +24,27 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
 
+	operatorv1client "open-cluster-management.io/api/client/operator/clientset/versioned/typed/operator/v1"
 	operatorinformer "open-cluster-management.io/api/client/operator/informers/externalversions/operator/v1"
 	operatorlister "open-cluster-management.io/api/client/operator/listers/operator/v1"
 
 	"github.com/stolostron/multicluster-controlplane/pkg/controllers/klusterlet/helpers"
 )
 
-const tlsCertFile = "tls.crt"
+const (
+	tlsCertFile = "tls.crt"
+
+	// bootstrapSecretHashAnnotation records the hash of the bootstrap secret that the klusterlet agent was
+	// bootstrapped with
+	bootstrapSecretHashAnnotation = "operator.open-cluster-management.io/bootstrap-secret-hash"
+
+	// BootstrapRetried is the condition type of the klusterlet that reports the agent is restarted to retry the
+	// bootstrap with a changed bootstrap secret
+	BootstrapRetried = "BootstrapRetried"
+)
 
 // BootstrapControllerSyncInterval is exposed so that integration tests can crank up the constroller sync speed.
 var BootstrapControllerSyncInterval = 5 * time.Minute
@@ -41,6 +55,7 @@ var BootstrapControllerSyncInterval = 5 * time.Minute
 // secret and restart the klusterlet agents
 type bootstrapController struct {
 	kubeClient       kubernetes.Interface
+	klusterletClient operatorv1client.KlusterletInterface
 	klusterletLister operatorlister.KlusterletLister
 	secretLister     corelister.SecretLister
 }
@@ -48,11 +63,13 @@ type bootstrapController struct {
 // NewBootstrapController returns a bootstrapController
 func NewBootstrapController(
 	kubeClient kubernetes.Interface,
+	klusterletClient operatorv1client.KlusterletInterface,
 	klusterletInformer operatorinformer.KlusterletInformer,
 	secretInformer coreinformer.SecretInformer,
 	recorder events.Recorder) factory.Controller {
 	controller := &bootstrapController{
 		kubeClient:       kubeClient,
+		klusterletClient: klusterletClient,
 		klusterletLister: klusterletInformer.Lister(),
 		secretLister:     secretInformer.Lister(),
 	}
@@ -97,6 +114,11 @@ func (k *bootstrapController) sync(ctx context.Context, controllerContext factor
 		return err
 	}
 
+	// the agents are not reloaded or restarted when the klusterlet is paused
+	if helpers.IsKlusterletPaused(klusterlet) {
+		return nil
+	}
+
 	bootstrapSecret := helpers.BootstrapHubKubeConfigSecret(klusterlet)
 	bootstrapHubKubeconfigSecret, err := k.secretLister.Secrets(agentNamespace).Get(bootstrapSecret)
 	switch {
@@ -115,16 +137,27 @@ func (k *bootstrapController) sync(ctx context.Context, controllerContext factor
 		return nil
 	}
 
+	bootstrapSecretHash := hashSecretData(bootstrapHubKubeconfigSecret)
+	lastBootstrapSecretHash := klusterlet.Annotations[bootstrapSecretHashAnnotation]
+	bootstrapSecretChanged := len(lastBootstrapSecretHash) != 0 && lastBootstrapSecretHash != bootstrapSecretHash
+
 	hubKubeConfig := helpers.HubKubeConfigSecret(klusterlet)
 	hubKubeconfigSecret, err := k.secretLister.Secrets(agentNamespace).Get(hubKubeConfig)
 	switch {
 	case errors.IsNotFound(err):
-		// the hub kubeconfig secret not found, could not have bootstrap yet, do nothing currently
-		// TODO one case should be supported in the future: the bootstrap phase may be failed due to
-		// the content of bootstrap secret is wrong, this also results in the hub kubeconfig secret
-		// cannot be found. In this case, user may need to correct the bootstrap secret. we need to
-		// find a way to know the bootstrap secret is corrected, and then reload the klusterlet
-		return nil
+		// the hub kubeconfig secret not found, the bootstrap may be failed due to the content of bootstrap secret
+		// is wrong, once the bootstrap secret is corrected, restart the agent to retry the bootstrap
+		if bootstrapSecretChanged {
+			reason := fmt.Sprintf("the bootstrap secret %s/%s is changed before the agent is bootstrapped",
+				agentNamespace, bootstrapSecret)
+			if err := k.restartAgent(ctx, controllerContext, agentNamespace, klusterletName, reason); err != nil {
+				return err
+			}
+			if err := k.updateBootstrapRetriedCondition(ctx, klusterletName, reason); err != nil {
+				return err
+			}
+		}
+		return k.recordBootstrapSecretHash(ctx, klusterletName, bootstrapSecretHash)
 	case err != nil:
 		return err
 	}
@@ -141,7 +174,27 @@ func (k *bootstrapController) sync(ctx context.Context, controllerContext factor
 		!bytes.Equal(bootstrapKubeconfig.CertificateAuthorityData, hubKubeconfig.CertificateAuthorityData) {
 		// the bootstrap kubeconfig secret is changed, reload the klusterlet agents
 		reloadReason := fmt.Sprintf("the bootstrap secret %s/%s is changed", agentNamespace, bootstrapSecret)
-		return k.reloadAgents(ctx, controllerContext, agentNamespace, hubKubeConfig, klusterletName, reloadReason)
+		if err := k.reloadAgents(ctx, controllerContext, agentNamespace, hubKubeConfig, klusterletName, reloadReason); err != nil {
+			return err
+		}
+		return k.recordBootstrapSecretHash(ctx, klusterletName, bootstrapSecretHash)
+	}
+
+	if bootstrapSecretChanged && isHubKubeconfigUnauthorized(hubKubeconfigSecret) {
+		// the hub kubeconfig is rejected by the hub, it may be bootstrapped with a wrong bootstrap secret, retry the
+		// bootstrap with the changed bootstrap secret
+		reloadReason := fmt.Sprintf("the bootstrap secret %s/%s is changed and the hub kubeconfig secret %s/%s is unauthorized",
+			agentNamespace, bootstrapSecret, agentNamespace, hubKubeConfig)
+		if err := k.reloadAgents(ctx, controllerContext, agentNamespace, hubKubeConfig, klusterletName, reloadReason); err != nil {
+			return err
+		}
+		if err := k.updateBootstrapRetriedCondition(ctx, klusterletName, reloadReason); err != nil {
+			return err
+		}
+	}
+
+	if err := k.recordBootstrapSecretHash(ctx, klusterletName, bootstrapSecretHash); err != nil {
+		return err
 	}
 
 	expired, err := isHubKubeconfigSecretExpired(hubKubeconfigSecret)
@@ -182,6 +235,55 @@ func (k *bootstrapController) reloadAgents(ctx context.Context, ctrlContext fact
 	return nil
 }
 
+// restartAgent restarts the klusterlet agent by deleting the agent deployment, the deployment will be recreated by the
+// klusterlet controller
+func (k *bootstrapController) restartAgent(ctx context.Context, ctrlContext factory.SyncContext, namespace, klusterletName, reason string) error {
+	agentName := fmt.Sprintf("%s-multicluster-controlplane-agent", klusterletName)
+	err := k.kubeClient.AppsV1().Deployments(namespace).Delete(ctx, agentName, metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ctrlContext.Recorder().Eventf("KlusterletAgentDeploymentDeleted", fmt.Sprintf("the deployment %s/%s is deleted due to %s",
+		namespace, agentName, reason))
+	return nil
+}
+
+func (k *bootstrapController) updateBootstrapRetriedCondition(ctx context.Context, klusterletName, reason string) error {
+	_, _, err := helpers.UpdateKlusterletStatus(ctx, k.klusterletClient, klusterletName,
+		helpers.UpdateKlusterletConditionFn(metav1.Condition{
+			Type:    BootstrapRetried,
+			Status:  metav1.ConditionTrue,
+			Reason:  "BootstrapSecretChanged",
+			Message: fmt.Sprintf("The agent is restarted to retry the bootstrap, because %s", reason),
+		}))
+	return err
+}
+
+// recordBootstrapSecretHash saves the hash of the current bootstrap secret to the klusterlet
+func (k *bootstrapController) recordBootstrapSecretHash(ctx context.Context, klusterletName, hash string) error {
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		klusterlet, err := k.klusterletClient.Get(ctx, klusterletName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		if klusterlet.Annotations[bootstrapSecretHashAnnotation] == hash {
+			return nil
+		}
+
+		klusterlet = klusterlet.DeepCopy()
+		if klusterlet.Annotations == nil {
+			klusterlet.Annotations = map[string]string{}
+		}
+		klusterlet.Annotations[bootstrapSecretHashAnnotation] = hash
+		_, err = k.klusterletClient.Update(ctx, klusterlet, metav1.UpdateOptions{})
+		return err
+	})
+}
+
 func (k *bootstrapController) loadKubeConfig(secret *corev1.Secret) (*clientcmdapi.Cluster, error) {
 	kubeconfig, ok := secret.Data["kubeconfig"]
 	if !ok {
@@ -209,16 +311,26 @@ func bootstrapSecretQueueKeyFunc(klusterletLister operatorlister.KlusterletListe
 			return ""
 		}
 		name := accessor.GetName()
-		if name != helpers.BootstrapHubKubeConfig && name != helpers.ControlplaneBootstrapHubKubeConfig {
-			return ""
-		}
-
 		namespace := accessor.GetNamespace()
 		klusterlets, err := klusterletLister.List(labels.Everything())
 		if err != nil {
 			return ""
 		}
 
+		// the klusterlet may have its own bootstrap secret, e.g. it is being migrated to another controlplane
+		for _, klusterlet := range klusterlets {
+			if _, ok := klusterlet.Annotations[helpers.BootstrapKubeConfigSecretAnnotation]; !ok {
+				continue
+			}
+			if helpers.BootstrapHubKubeConfigSecret(klusterlet) == name && helpers.AgentNamespace(klusterlet) == namespace {
+				return namespace + "/" + klusterlet.Name
+			}
+		}
+
+		if name != helpers.BootstrapHubKubeConfig && name != helpers.ControlplaneBootstrapHubKubeConfig {
+			return ""
+		}
+
 		if klusterlet := helpers.FindKlusterletByNamespace(klusterlets, namespace); klusterlet != nil {
 			return namespace + "/" + klusterlet.Name
 		}
@@ -227,6 +339,38 @@ func bootstrapSecretQueueKeyFunc(klusterletLister operatorlister.KlusterletListe
 	}
 }
 
+func hashSecretData(secret *corev1.Secret) string {
+	keys := make([]string, 0, len(secret.Data))
+	for key := range secret.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		hash.Write([]byte(key))
+		hash.Write(secret.Data[key])
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil))
+}
+
+// isHubKubeconfigUnauthorized checks whether the hub rejects the credential in the hub kubeconfig secret
+func isHubKubeconfigUnauthorized(secret *corev1.Secret) bool {
+	config, err := helpers.LoadClientConfigFromSecret(secret)
+	if err != nil {
+		return false
+	}
+	config.Timeout = 10 * time.Second
+
+	hubClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return false
+	}
+
+	_, err = hubClient.Discovery().ServerVersion()
+	return errors.IsUnauthorized(err)
+}
+
 func isHubKubeconfigSecretExpired(secret *corev1.Secret) (bool, error) {
 	certData, ok := secret.Data[tlsCertFile]
 	if !ok {
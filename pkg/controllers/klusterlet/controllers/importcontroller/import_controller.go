@@ -0,0 +1,379 @@
+// Copyright Contributors to the Open Cluster Management project
+package importcontroller
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/openshift/library-go/pkg/assets"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	clusterinformer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	clusterlister "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	operatorinformer "open-cluster-management.io/api/client/operator/informers/externalversions/operator/v1"
+	operatorlister "open-cluster-management.io/api/client/operator/listers/operator/v1"
+	workclient "open-cluster-management.io/api/client/work/clientset/versioned"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+	"open-cluster-management.io/multicluster-controlplane/pkg/controllers/ocmcontroller"
+
+	"github.com/stolostron/multicluster-controlplane/pkg/controllers/klusterlet/helpers"
+	"github.com/stolostron/multicluster-controlplane/pkg/controllers/klusterlet/manifests"
+)
+
+const (
+	// ImportSecretKey is the key of the import manifests in the import secret
+	ImportSecretKey = "import.yaml"
+
+	// the expiration timestamp of the bootstrap token in the import secret
+	tokenExpirationAnnotation = "import.open-cluster-management.io/token-expiration-timestamp"
+	// the agent image in the import secret
+	agentImageAnnotation = "import.open-cluster-management.io/agent-image"
+
+	// the bootstrap kubeconfig secret of the agent on the managed cluster, see import/bootstrap-kubeconfig.yaml
+	agentNamespace            = "multicluster-controlplane-agent"
+	bootstrapKubeConfigSecret = "bootstrap-kubeconfig"
+
+	// bootstrapKubeConfigWorkName is the name of the ManifestWork that refreshes the bootstrap kubeconfig secret of
+	// the joined managed cluster.
+	bootstrapKubeConfigWorkName = "multicluster-controlplane-agent-bootstrap-kubeconfig"
+)
+
+var (
+	// ImportTokenExpiration is the lifetime of the bootstrap token in the import secret, the token is refreshed after
+	// four fifths of its lifetime is passed.
+	ImportTokenExpiration = 24 * time.Hour
+
+	// the import manifests are rendered in this order, so they can be applied on the managed cluster directly
+	importManifestFiles = []string{
+		"import/namespace.yaml",
+		"import/serviceaccount.yaml",
+		"import/clusterrole.yaml",
+		"import/clusterrolebinding.yaml",
+		"import/clusterrolebinding-admin.yaml",
+		"import/role.yaml",
+		"import/rolebinding.yaml",
+		"import/bootstrap-kubeconfig.yaml",
+		"import/deployment.yaml",
+	}
+)
+
+// ImportSecretName returns the name of the import secret of a managed cluster, the secret is in the managed cluster
+// namespace on the controlplane.
+func ImportSecretName(clusterName string) string {
+	return fmt.Sprintf("%s-import", clusterName)
+}
+
+// importConfig is used to render the template of import manifests
+type importConfig struct {
+	ClusterName         string
+	AgentImage          string
+	BootstrapKubeConfig string
+	BootstrapToken      string
+}
+
+// importController renders the manifests to deploy the agent in the default mode for the pending managed clusters,
+// the manifests are saved in the import secret of each managed cluster, user can apply them on the managed cluster
+// to join the managed cluster to the controlplane.
+// The bootstrap kubeconfig in the manifests uses a short-lived token of a service account in the managed cluster
+// namespace, the service account is only allowed to bootstrap the managed cluster and access the resources of the
+// agent addons in the managed cluster namespace. After the managed cluster is joined, the agent addons keep using the
+// bootstrap kubeconfig, so its token is refreshed with a ManifestWork until the managed cluster is deleted.
+type importController struct {
+	controlplaneKubeClient kubernetes.Interface
+	controlplaneWorkClient workclient.Interface
+	kubeClient             kubernetes.Interface
+	clusterLister          clusterlister.ManagedClusterLister
+	klusterletLister       operatorlister.KlusterletLister
+}
+
+// NewImportController returns an importController, the kube client is for the management cluster that the
+// controlplane is running on.
+func NewImportController(
+	controlplaneKubeClient kubernetes.Interface,
+	controlplaneWorkClient workclient.Interface,
+	kubeClient kubernetes.Interface,
+	clusterInformer clusterinformer.ManagedClusterInformer,
+	klusterletInformer operatorinformer.KlusterletInformer,
+	recorder events.Recorder) factory.Controller {
+	controller := &importController{
+		controlplaneKubeClient: controlplaneKubeClient,
+		controlplaneWorkClient: controlplaneWorkClient,
+		kubeClient:             kubeClient,
+		clusterLister:          clusterInformer.Lister(),
+		klusterletLister:       klusterletInformer.Lister(),
+	}
+	return factory.New().WithSync(controller.sync).
+		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			return accessor.GetName()
+		}, clusterInformer.Informer(), klusterletInformer.Informer()).
+		ToController("ImportController", recorder)
+}
+
+func (c *importController) sync(ctx context.Context, controllerContext factory.SyncContext) error {
+	clusterName := controllerContext.QueueKey()
+	if clusterName == "" || clusterName == factory.DefaultQueueKey {
+		return nil
+	}
+
+	klog.V(4).Infof("Reconciling import secret of managed cluster %q", clusterName)
+
+	cluster, err := c.clusterLister.Get(clusterName)
+	switch {
+	case errors.IsNotFound(err):
+		return c.cleanup(ctx, clusterName)
+	case err != nil:
+		return err
+	}
+
+	if !cluster.DeletionTimestamp.IsZero() {
+		return c.cleanup(ctx, clusterName)
+	}
+
+	// the managed cluster is imported by a klusterlet, the import secret is not required
+	_, err = c.klusterletLister.Get(clusterName)
+	switch {
+	case err == nil:
+		return c.deleteImportSecret(ctx, clusterName)
+	case !errors.IsNotFound(err):
+		return err
+	}
+
+	// the managed cluster is joined, the import secret is not required anymore. The bootstrap service account is
+	// kept, because the agent addons access the controlplane with the bootstrap kubeconfig.
+	if meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1.ManagedClusterConditionJoined) {
+		if err := c.deleteImportSecret(ctx, clusterName); err != nil {
+			return err
+		}
+
+		// the agent of the self management cluster uses the controlplane kubeconfig
+		if _, ok := cluster.Labels[ocmcontroller.SelfManagementClusterLabel]; ok {
+			return nil
+		}
+		return c.refreshBootstrapKubeConfig(ctx, controllerContext, clusterName)
+	}
+
+	image, err := helpers.GetControlplaneImage(ctx, c.kubeClient)
+	if err != nil {
+		return err
+	}
+
+	importSecret, err := c.controlplaneKubeClient.CoreV1().Secrets(clusterName).Get(
+		ctx, ImportSecretName(clusterName), metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		importSecret = nil
+	case err != nil:
+		return err
+	}
+
+	if importSecret != nil && importSecret.Annotations[agentImageAnnotation] == image {
+		refreshTime := tokenRefreshTime(importSecret.Annotations)
+		if time.Now().Before(refreshTime) {
+			controllerContext.Queue().AddAfter(clusterName, time.Until(refreshTime))
+			return nil
+		}
+	}
+
+	if err := helpers.EnsureBootstrapServiceAccount(
+		ctx, c.controlplaneKubeClient, clusterName, controllerContext.Recorder()); err != nil {
+		return err
+	}
+
+	bootstrapKubeConfig, expiration, err := c.buildBootstrapKubeConfig(ctx, clusterName)
+	if err != nil {
+		return err
+	}
+
+	importManifests, err := renderImportManifests(importConfig{
+		ClusterName: clusterName,
+		AgentImage:  image,
+		BootstrapKubeConfig: base64.StdEncoding.EncodeToString(
+			bootstrapKubeConfig[helpers.BootstrapKubeConfigSecretKey]),
+		BootstrapToken: base64.StdEncoding.EncodeToString(bootstrapKubeConfig[helpers.BootstrapTokenSecretKey]),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, _, err = resourceapply.ApplySecret(ctx, c.controlplaneKubeClient.CoreV1(), controllerContext.Recorder(),
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      ImportSecretName(clusterName),
+				Namespace: clusterName,
+				Annotations: map[string]string{
+					tokenExpirationAnnotation: expiration.Format(time.RFC3339),
+					agentImageAnnotation:      image,
+				},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: map[string][]byte{ImportSecretKey: importManifests},
+		})
+	if err != nil {
+		return err
+	}
+
+	controllerContext.Queue().AddAfter(clusterName, time.Until(expiration.Add(-ImportTokenExpiration/5)))
+	return nil
+}
+
+// refreshBootstrapKubeConfig refreshes the bootstrap kubeconfig secret on the joined managed cluster with a
+// ManifestWork, the agent addons reload the token from the secret.
+func (c *importController) refreshBootstrapKubeConfig(
+	ctx context.Context, controllerContext factory.SyncContext, clusterName string) error {
+	// the managed cluster is not imported with the import secret
+	_, err := c.controlplaneKubeClient.CoreV1().ServiceAccounts(clusterName).Get(
+		ctx, helpers.BootstrapServiceAccountName(clusterName), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	work, err := c.controlplaneWorkClient.WorkV1().ManifestWorks(clusterName).Get(
+		ctx, bootstrapKubeConfigWorkName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		work = nil
+	case err != nil:
+		return err
+	}
+
+	if work != nil {
+		refreshTime := tokenRefreshTime(work.Annotations)
+		if time.Now().Before(refreshTime) {
+			controllerContext.Queue().AddAfter(clusterName, time.Until(refreshTime))
+			return nil
+		}
+	}
+
+	// the rules of the bootstrap service account may be changed after the managed cluster was imported
+	if err := helpers.EnsureBootstrapServiceAccount(
+		ctx, c.controlplaneKubeClient, clusterName, controllerContext.Recorder()); err != nil {
+		return err
+	}
+
+	bootstrapKubeConfig, expiration, err := c.buildBootstrapKubeConfig(ctx, clusterName)
+	if err != nil {
+		return err
+	}
+
+	requiredWork := &workv1.ManifestWork{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      bootstrapKubeConfigWorkName,
+			Namespace: clusterName,
+			Annotations: map[string]string{
+				tokenExpirationAnnotation: expiration.Format(time.RFC3339),
+			},
+		},
+		Spec: workv1.ManifestWorkSpec{
+			Workload: workv1.ManifestsTemplate{
+				Manifests: []workv1.Manifest{{RawExtension: runtime.RawExtension{Object: &corev1.Secret{
+					TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      bootstrapKubeConfigSecret,
+						Namespace: agentNamespace,
+					},
+					Type: corev1.SecretTypeOpaque,
+					Data: bootstrapKubeConfig,
+				}}}},
+			},
+			// the agent is still running with the bootstrap kubeconfig secret after the work is deleted
+			DeleteOption: &workv1.DeleteOption{PropagationPolicy: workv1.DeletePropagationPolicyTypeOrphan},
+		},
+	}
+
+	if work == nil {
+		_, err = c.controlplaneWorkClient.WorkV1().ManifestWorks(clusterName).Create(
+			ctx, requiredWork, metav1.CreateOptions{})
+	} else {
+		work = work.DeepCopy()
+		work.Annotations = requiredWork.Annotations
+		work.Spec = requiredWork.Spec
+		_, err = c.controlplaneWorkClient.WorkV1().ManifestWorks(clusterName).Update(ctx, work, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+
+	controllerContext.Queue().AddAfter(clusterName, time.Until(expiration.Add(-ImportTokenExpiration/5)))
+	return nil
+}
+
+// buildBootstrapKubeConfig builds the data of the bootstrap kubeconfig secret with a new bootstrap token, the server
+// and ca of the controlplane are from the cluster-info configmap.
+func (c *importController) buildBootstrapKubeConfig(
+	ctx context.Context, clusterName string) (map[string][]byte, time.Time, error) {
+	server, caData, err := helpers.ControlplaneServerAndCA(ctx, c.controlplaneKubeClient)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return helpers.BuildBootstrapKubeConfig(
+		ctx, c.controlplaneKubeClient, clusterName, server, caData, ImportTokenExpiration)
+}
+
+func (c *importController) deleteImportSecret(ctx context.Context, clusterName string) error {
+	err := c.controlplaneKubeClient.CoreV1().Secrets(clusterName).Delete(
+		ctx, ImportSecretName(clusterName), metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// cleanup removes the import secret, the bootstrap kubeconfig work and the bootstrap service account after the
+// managed cluster is deleted
+func (c *importController) cleanup(ctx context.Context, clusterName string) error {
+	if err := c.deleteImportSecret(ctx, clusterName); err != nil {
+		return err
+	}
+
+	err := c.controlplaneWorkClient.WorkV1().ManifestWorks(clusterName).Delete(
+		ctx, bootstrapKubeConfigWorkName, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	return helpers.DeleteBootstrapServiceAccount(ctx, c.controlplaneKubeClient, clusterName)
+}
+
+func renderImportManifests(config importConfig) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	for _, name := range importManifestFiles {
+		template, err := manifests.ImportManifestFiles.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.WriteString("---\n")
+		buf.Write(bytes.TrimSpace(assets.MustCreateAssetFromTemplate(name, template, config).Data))
+		buf.WriteString("\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// tokenRefreshTime returns the time to refresh the bootstrap token in the import secret or the bootstrap kubeconfig
+// work with the annotations, an object without a valid expiration timestamp is refreshed immediately.
+func tokenRefreshTime(annotations map[string]string) time.Time {
+	expiration, err := time.Parse(time.RFC3339, annotations[tokenExpirationAnnotation])
+	if err != nil {
+		return time.Time{}
+	}
+	return expiration.Add(-ImportTokenExpiration / 5)
+}
@@ -3,9 +3,13 @@ package klusterletcontroller
 
 import (
 	"context"
+	"crypto/x509"
+	goerrors "errors"
 	"fmt"
+	"net"
 	"reflect"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/openshift/library-go/pkg/assets"
@@ -36,7 +40,20 @@ import (
 	"github.com/stolostron/multicluster-controlplane/pkg/controllers/klusterlet/manifests"
 )
 
+const (
+	// managedResourcesEvictionGracePeriodAnno overrides the default managed resources eviction grace period of a
+	// klusterlet, its value is a duration, e.g. 10m
+	managedResourcesEvictionGracePeriodAnno = "operator.open-cluster-management.io/managed-resources-eviction-grace-period"
+
+	// klusterletCleaning reports the cleanup progress of a deleting klusterlet, it is true when the cleanup is in
+	// progress, and false when the cleanup is blocked by a failure
+	klusterletCleaning = "Cleaning"
+	// klusterletManagedResourcesOrphaned reports the resources that are left behind on the managed cluster
+	klusterletManagedResourcesOrphaned = "ManagedResourcesOrphaned"
+)
+
 type klusterletCleanupController struct {
+	evictionGracePeriod       time.Duration
 	kubeClient                kubernetes.Interface
 	controlplaneKubeClient    kubernetes.Interface
 	controlplaneDynamicClient dynamic.Interface
@@ -61,8 +78,10 @@ func NewKlusterletCleanupController(
 	secretInformer coreinformer.SecretInformer,
 	deploymentInformer appsinformer.DeploymentInformer,
 	appliedManifestWorkClient workv1client.AppliedManifestWorkInterface,
+	evictionGracePeriod time.Duration,
 	recorder events.Recorder) factory.Controller {
 	controller := &klusterletCleanupController{
+		evictionGracePeriod:       evictionGracePeriod,
 		kubeClient:                kubeClient,
 		controlplaneKubeClient:    controlplaneKubeClient,
 		controlplaneDynamicClient: controlplaneDynamicClient,
@@ -98,6 +117,11 @@ func (n *klusterletCleanupController) sync(ctx context.Context, controllerContex
 
 	klusterlet = klusterlet.DeepCopy()
 
+	// the klusterlet is cleaned up after it is resumed
+	if helpers.IsKlusterletPaused(klusterlet) {
+		return nil
+	}
+
 	if klusterlet.DeletionTimestamp.IsZero() {
 		if !hasFinalizer(klusterlet, klusterletFinalizer) {
 			return n.addFinalizer(ctx, klusterlet, klusterletFinalizer)
@@ -128,7 +152,24 @@ func (n *klusterletCleanupController) sync(ctx context.Context, controllerContex
 
 	// cleanup managed cluster and its manifest works on the controlplane in hosted mode
 	if config.InstallMode == operatorapiv1.InstallModeHosted {
-		hasRemainedWorks, err := n.deleteManifestWorks(ctx, config)
+		detached := helpers.IsKlusterletDetached(klusterlet)
+		// the agent of the migrated klusterlet has been rebootstrapped to the target controlplane, so its manifest
+		// works on this controlplane can be removed safely before the agent is removed
+		if detached && !helpers.IsKlusterletMigratedOut(klusterlet) {
+			// the agent should be stopped before its manifest works are removed, otherwise the agent will remove
+			// the applied resources of the manifest works from the managed cluster
+			stopped, err := n.stopAgent(ctx, config)
+			if err != nil {
+				return err
+			}
+
+			if !stopped {
+				controllerContext.Queue().AddAfter(config.KlusterletName, 5*time.Second)
+				return nil
+			}
+		}
+
+		hasRemainedWorks, err := n.deleteManifestWorks(ctx, config, detached)
 		if err != nil {
 			return err
 		}
@@ -169,17 +210,24 @@ func (n *klusterletCleanupController) sync(ctx context.Context, controllerContex
 		}
 
 		// check the managed cluster connectivity
+		gracePeriod := n.managedResourcesEvictionGracePeriod(klusterlet)
 		cleanupManagedClusterResources, err := n.checkConnectivity(
-			ctx, managedClusterClients.appliedManifestWorkClient, klusterlet)
+			ctx, managedClusterClients.appliedManifestWorkClient, klusterlet, gracePeriod)
 		if err != nil {
 			errs := []error{err}
 			// compare the annotation to check whether the eviction timestamp has changed
 			if err := n.updateKlusterletAnnotation(ctx, klusterlet.Name, klusterlet.Annotations); err != nil {
 				errs = append(errs, err)
 			}
+			if _, _, err := helpers.UpdateKlusterletStatus(ctx, n.klusterletClient, klusterlet.Name,
+				helpers.UpdateKlusterletConditionFn(cleaningCondition(klusterlet, gracePeriod, err))); err != nil {
+				errs = append(errs, err)
+			}
 			return utilerrors.NewAggregate(errs)
 		}
 
+		conds := []metav1.Condition{cleaningCondition(klusterlet, gracePeriod, nil)}
+
 		// after trying to connect to the managed cluster times out for a period of time, we will stop removing
 		// resources on managed clusters, but just clean the resources on the hosting cluster and finish the cleanup
 		if cleanupManagedClusterResources {
@@ -194,6 +242,23 @@ func (n *klusterletCleanupController) sync(ctx context.Context, controllerContex
 					recorder:              controllerContext.Recorder(),
 				},
 			)
+		} else {
+			leftResources := managedResourcesLeftBehind(klusterlet, config)
+			controllerContext.Recorder().Warningf("ManagedResourcesOrphaned",
+				"The managed cluster of klusterlet %s is unreachable for %s, the resources %v are left behind",
+				klusterlet.Name, gracePeriod, leftResources)
+			conds = append(conds, metav1.Condition{
+				Type:   klusterletManagedResourcesOrphaned,
+				Status: metav1.ConditionTrue,
+				Reason: "ManagedResourcesEvictionGracePeriodExceeded",
+				Message: fmt.Sprintf("The managed cluster is unreachable for %s, these resources are left behind "+
+					"on the managed cluster: %s", gracePeriod, strings.Join(leftResources, ", ")),
+			})
+		}
+
+		if _, _, err := helpers.UpdateKlusterletStatus(ctx, n.klusterletClient, klusterlet.Name,
+			helpers.UpdateKlusterletConditionFn(conds...)); err != nil {
+			return err
 		}
 	}
 
@@ -244,6 +309,15 @@ func (n *klusterletCleanupController) sync(ctx context.Context, controllerContex
 			return err
 		}
 
+		// the bootstrap kubeconfig secret is owned by the klusterlet when it is overridden, e.g. the bootstrap
+		// kubeconfig of the target controlplane that the agent of a migrated klusterlet is rebootstrapped with
+		if _, ok := klusterlet.Annotations[helpers.BootstrapKubeConfigSecretAnnotation]; ok {
+			if err := n.kubeClient.CoreV1().Secrets(config.AgentNamespace).Delete(
+				ctx, config.BootStrapKubeConfigSecret, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+
 		// remove namespace in managedcluster if klusterlet is not created by user
 		if createdByController != "" {
 			if err := n.controlplaneKubeClient.CoreV1().Namespaces().
@@ -258,15 +332,16 @@ func (n *klusterletCleanupController) sync(ctx context.Context, controllerContex
 
 func (n *klusterletCleanupController) checkConnectivity(ctx context.Context,
 	amwClient workv1client.AppliedManifestWorkInterface,
-	klusterlet *operatorapiv1.Klusterlet) (cleanupManagedClusterResources bool, err error) {
+	klusterlet *operatorapiv1.Klusterlet,
+	gracePeriod time.Duration) (cleanupManagedClusterResources bool, err error) {
 	_, err = amwClient.List(ctx, metav1.ListOptions{})
 	if err == nil {
 		return true, nil
 	}
 
-	// if the managed cluster is destroyed, the returned err is TCP timeout or TCP no such host,
-	// the k8s.io/apimachinery/pkg/api/errors.IsTimeout,IsServerTimeout can not match this error
-	if isTCPTimeOutError(err) || isTCPNoSuchHostError(err) {
+	// if the managed cluster is destroyed or its credential is revoked, the returned err is a network, TLS or
+	// unauthorized error, the k8s.io/apimachinery/pkg/api/errors.IsTimeout,IsServerTimeout can not match this error
+	if isManagedClusterUnreachableError(err) {
 		klog.V(4).Infof("Check the connectivity for klusterlet %s, annotation: %s, err: %v",
 			klusterlet.Name, klusterlet.Annotations, err)
 		if klusterlet.Annotations == nil {
@@ -285,9 +360,9 @@ func (n *klusterletCleanupController) checkConnectivity(ctx context.Context,
 			return true, err
 		}
 
-		if evictionTime.Add(5 * time.Minute).Before(time.Now()) {
-			klog.Infof("Try to connect managed cluster timed out for 5 minutes, klusterlet %s, ignore the resources",
-				klusterlet.Name)
+		if evictionTime.Add(gracePeriod).Before(time.Now()) {
+			klog.Infof("Try to connect managed cluster timed out for %s, klusterlet %s, ignore the resources",
+				gracePeriod, klusterlet.Name)
 			// ignore the resources on the managed cluster, return false here
 			return false, nil
 		}
@@ -300,6 +375,24 @@ func (n *klusterletCleanupController) checkConnectivity(ctx context.Context,
 	return true, err
 }
 
+// managedResourcesEvictionGracePeriod returns the managed resources eviction grace period of the klusterlet, the
+// default grace period is used if the klusterlet does not have a valid one.
+func (n *klusterletCleanupController) managedResourcesEvictionGracePeriod(klusterlet *operatorapiv1.Klusterlet) time.Duration {
+	gracePeriodStr, ok := klusterlet.Annotations[managedResourcesEvictionGracePeriodAnno]
+	if !ok {
+		return n.evictionGracePeriod
+	}
+
+	gracePeriod, err := time.ParseDuration(gracePeriodStr)
+	if err != nil || gracePeriod < 0 {
+		klog.Warningf("Invalid managed resources eviction grace period %q for klusterlet %s, use the default %s",
+			gracePeriodStr, klusterlet.Name, n.evictionGracePeriod)
+		return n.evictionGracePeriod
+	}
+
+	return gracePeriod
+}
+
 func (n *klusterletCleanupController) updateKlusterletAnnotation(
 	ctx context.Context, klusterletName string, annotations map[string]string) error {
 	// reload klusterlet
@@ -345,7 +438,31 @@ func (n *klusterletCleanupController) removeKlusterletFinalizers(ctx context.Con
 	return nil
 }
 
-func (n *klusterletCleanupController) deleteManifestWorks(ctx context.Context, config klusterletConfig) (bool, error) {
+// stopAgent deletes the agent deployment and returns true once the deployment and its pods are gone
+func (n *klusterletCleanupController) stopAgent(ctx context.Context, config klusterletConfig) (bool, error) {
+	agentName := fmt.Sprintf("%s-multicluster-controlplane-agent", config.KlusterletName)
+	_, err := n.kubeClient.AppsV1().Deployments(config.AgentNamespace).Get(ctx, agentName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	foreground := metav1.DeletePropagationForeground
+	err = n.kubeClient.AppsV1().Deployments(config.AgentNamespace).Delete(ctx, agentName, metav1.DeleteOptions{
+		PropagationPolicy: &foreground,
+	})
+	if err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+
+	return false, nil
+}
+
+// deleteManifestWorks deletes the manifest works of the managed cluster, the manifest works are force deleted when the
+// klusterlet is detached, so the applied resources are kept on the managed cluster.
+func (n *klusterletCleanupController) deleteManifestWorks(ctx context.Context, config klusterletConfig, detached bool) (bool, error) {
 	works, err := n.controlplaneWorkClient.WorkV1().ManifestWorks(config.ClusterName).List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return false, err
@@ -355,6 +472,13 @@ func (n *klusterletCleanupController) deleteManifestWorks(ctx context.Context, c
 		return false, nil
 	}
 
+	if detached {
+		if err := helpers.DeleteAllManifestWorks(ctx, n.controlplaneWorkClient, works.Items, true); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
 	unavailable, err := helpers.IsClusterUnavailable(ctx, n.controlplaneClusterClient, config.ClusterName)
 	if err != nil {
 		return false, err
@@ -375,6 +499,103 @@ func (n *klusterletCleanupController) deleteManifestWorks(ctx context.Context, c
 	return true, nil
 }
 
+// cleaningCondition builds the cleanup progress condition of the klusterlet with the connectivity check result, the
+// condition is false if the connectivity check fails
+func cleaningCondition(klusterlet *operatorapiv1.Klusterlet, gracePeriod time.Duration, err error) metav1.Condition {
+	switch {
+	case err == nil:
+		return metav1.Condition{
+			Type:    klusterletCleaning,
+			Status:  metav1.ConditionTrue,
+			Reason:  "KlusterletCleaningUp",
+			Message: "Cleaning up the klusterlet resources",
+		}
+	case isManagedClusterUnreachableError(err):
+		return metav1.Condition{
+			Type:   klusterletCleaning,
+			Status: metav1.ConditionFalse,
+			Reason: "ManagedClusterUnreachable",
+			Message: fmt.Sprintf("The managed cluster is unreachable since %s, the resources on the managed cluster "+
+				"will be left behind after %s: %v",
+				klusterlet.Annotations[managedResourcesEvictionTimestampAnno], gracePeriod, err),
+		}
+	default:
+		return metav1.Condition{
+			Type:    klusterletCleaning,
+			Status:  metav1.ConditionFalse,
+			Reason:  "KlusterletCleanupFailed",
+			Message: fmt.Sprintf("Failed to check the managed cluster connectivity: %v", err),
+		}
+	}
+}
+
+// managedResourcesLeftBehind returns the resources on the managed cluster that are not cleaned up by the klusterlet
+func managedResourcesLeftBehind(klusterlet *operatorapiv1.Klusterlet, config klusterletConfig) []string {
+	resources := []string{}
+	for _, name := range append(append([]string{}, crdV1StaticFiles...), managedStaticResourceFiles...) {
+		template, err := manifests.KlusterletManifestFiles.ReadFile(name)
+		if err != nil {
+			continue
+		}
+		resource, err := helpers.GenerateRelatedResource(assets.MustCreateAssetFromTemplate(name, template, config).Data)
+		if err != nil {
+			continue
+		}
+		resources = append(resources, formatRelatedResource(resource))
+	}
+
+	for _, namespace := range []string{config.KlusterletNamespace, fmt.Sprintf("%s-addon", config.KlusterletNamespace)} {
+		resources = append(resources, fmt.Sprintf("namespaces/%s", namespace))
+	}
+
+	return append(resources, fmt.Sprintf("appliedmanifestworks.work.open-cluster-management.io of the agent %s",
+		klusterlet.UID))
+}
+
+func formatRelatedResource(resource operatorapiv1.RelatedResourceMeta) string {
+	gr := resource.Resource
+	if len(resource.Group) > 0 {
+		gr = fmt.Sprintf("%s.%s", resource.Resource, resource.Group)
+	}
+	if len(resource.Namespace) > 0 {
+		return fmt.Sprintf("%s/%s/%s", gr, resource.Namespace, resource.Name)
+	}
+	return fmt.Sprintf("%s/%s", gr, resource.Name)
+}
+
+// isManagedClusterUnreachableError checks whether the error is caused by the managed cluster cannot be reached
+// anymore, e.g. the managed cluster is destroyed, its certificate is changed or the credential is revoked.
+func isManagedClusterUnreachableError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if isTCPTimeOutError(err) || isTCPNoSuchHostError(err) || isConnectionRefusedError(err) ||
+		isTLSError(err) || errors.IsUnauthorized(err) {
+		return true
+	}
+
+	var netErr net.Error
+	return goerrors.As(err, &netErr) && netErr.Timeout()
+}
+
+func isConnectionRefusedError(err error) bool {
+	return goerrors.Is(err, syscall.ECONNREFUSED) ||
+		strings.Contains(err.Error(), "connection refused") ||
+		strings.Contains(err.Error(), "no route to host")
+}
+
+func isTLSError(err error) bool {
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var certInvalidErr x509.CertificateInvalidError
+	var hostnameErr x509.HostnameError
+	if goerrors.As(err, &unknownAuthorityErr) || goerrors.As(err, &certInvalidErr) || goerrors.As(err, &hostnameErr) {
+		return true
+	}
+
+	return strings.Contains(err.Error(), "x509: ") || strings.Contains(err.Error(), "tls: ")
+}
+
 func isTCPTimeOutError(err error) bool {
 	return err != nil &&
 		strings.Contains(err.Error(), "dial tcp") &&
@@ -4,6 +4,7 @@ package klusterletcontroller
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
@@ -25,9 +26,11 @@ import (
 	operatorinformer "open-cluster-management.io/api/client/operator/informers/externalversions/operator/v1"
 	operatorlister "open-cluster-management.io/api/client/operator/listers/operator/v1"
 	workv1client "open-cluster-management.io/api/client/work/clientset/versioned/typed/work/v1"
+	ocmfeature "open-cluster-management.io/api/feature"
 	operatorapiv1 "open-cluster-management.io/api/operator/v1"
 
 	"github.com/stolostron/multicluster-controlplane/pkg/controllers/klusterlet/helpers"
+	"github.com/stolostron/multicluster-controlplane/pkg/feature"
 )
 
 const (
@@ -50,6 +53,7 @@ type klusterletController struct {
 	klusterletClient          operatorv1client.KlusterletInterface
 	klusterletLister          operatorlister.KlusterletLister
 	cache                     resourceapply.ResourceCache
+	managedClusterTokenTTL    time.Duration
 }
 
 type klusterletReconcile interface {
@@ -74,6 +78,7 @@ func NewKlusterletController(
 	secretInformer coreinformer.SecretInformer,
 	deploymentInformer appsinformer.DeploymentInformer,
 	appliedManifestWorkClient workv1client.AppliedManifestWorkInterface,
+	managedClusterTokenTTL time.Duration,
 	recorder events.Recorder) factory.Controller {
 	controller := &klusterletController{
 		kubeClient:                kubeClient,
@@ -83,6 +88,7 @@ func NewKlusterletController(
 		klusterletClient:          klusterletClient,
 		klusterletLister:          klusterletInformer.Lister(),
 		cache:                     resourceapply.NewResourceCache(),
+		managedClusterTokenTTL:    managedClusterTokenTTL,
 	}
 
 	return factory.New().WithSync(controller.sync).
@@ -121,7 +127,11 @@ type klusterletConfig struct {
 
 	InstallMode operatorapiv1.InstallMode
 
-	// TODO support to configure standalone agent features
+	// RegistrationFeatureGates, WorkFeatureGates and AddOnFeatureGates are the feature gate flags of the agent,
+	// only the features that are different from their defaults are set.
+	RegistrationFeatureGates []string
+	WorkFeatureGates         []string
+	AddOnFeatureGates        []string
 }
 
 func (n *klusterletController) sync(ctx context.Context, controllerContext factory.SyncContext) error {
@@ -139,6 +149,17 @@ func (n *klusterletController) sync(ctx context.Context, controllerContext facto
 
 	klusterlet = klusterlet.DeepCopy()
 
+	// the klusterlet is migrated from another controlplane, its agent is still running for the source controlplane,
+	// the klusterlet is reconciled after the migration is completed
+	if klusterlet.DeletionTimestamp.IsZero() && helpers.IsKlusterletMigrating(klusterlet) {
+		return nil
+	}
+
+	// the klusterlet is paused, its status is reported by the pause controller
+	if helpers.IsKlusterletPaused(klusterlet) {
+		return nil
+	}
+
 	image, err := getAgentImage(ctx, n.kubeClient, klusterlet)
 	if err != nil {
 		return err
@@ -157,6 +178,8 @@ func (n *klusterletController) sync(ctx context.Context, controllerContext facto
 		InstallMode:                            klusterlet.Spec.DeployOption.Mode,
 	}
 
+	featureGateCondition := buildFeatureGates(klusterlet, &config)
+
 	managedClusterClients, err := newManagedClusterClientsBuilder(
 		klusterlet,
 		n.kubeClient,
@@ -200,8 +223,6 @@ func (n *klusterletController) sync(ctx context.Context, controllerContext facto
 		return nil
 	}
 
-	// TODO support to configure standalone agent features
-
 	reconcilers := []klusterletReconcile{
 		&crdReconcile{
 			managedClusterClients: managedClusterClients,
@@ -220,7 +241,8 @@ func (n *klusterletController) sync(ctx context.Context, controllerContext facto
 			managedClusterClients: managedClusterClients,
 			kubeClient:            n.kubeClient,
 			recorder:              controllerContext.Recorder(),
-			cache:                 n.cache},
+			cache:                 n.cache,
+			tokenTTL:              n.managedClusterTokenTTL},
 	}
 
 	var errs []error
@@ -249,7 +271,7 @@ func (n *klusterletController) sync(ctx context.Context, controllerContext facto
 
 		// When appliedCondition is false, we should not update related resources and resource generations
 		_, updated, err := helpers.UpdateKlusterletStatus(ctx, n.klusterletClient, klusterletName,
-			helpers.UpdateKlusterletConditionFn(*appliedCondition),
+			helpers.UpdateKlusterletConditionFn(*appliedCondition, featureGateCondition),
 			func(oldStatus *operatorapiv1.KlusterletStatus) error {
 				oldStatus.ObservedGeneration = klusterlet.Generation
 				return nil
@@ -265,7 +287,7 @@ func (n *klusterletController) sync(ctx context.Context, controllerContext facto
 
 	// If we get here, we have successfully applied everything.
 	_, _, err = helpers.UpdateKlusterletStatus(ctx, n.klusterletClient, klusterletName,
-		helpers.UpdateKlusterletConditionFn(*appliedCondition),
+		helpers.UpdateKlusterletConditionFn(*appliedCondition, featureGateCondition),
 		helpers.UpdateKlusterletGenerationsFn(klusterlet.Status.Generations...),
 		helpers.UpdateKlusterletRelatedResourcesFn(klusterlet.Status.RelatedResources...),
 		func(oldStatus *operatorapiv1.KlusterletStatus) error {
@@ -276,6 +298,28 @@ func (n *klusterletController) sync(ctx context.Context, controllerContext facto
 	return err
 }
 
+// buildFeatureGates sets the feature gate flags of the agent from the registration and work configurations and the
+// addon feature gates annotation of the klusterlet, and returns the condition to report the invalid feature gates.
+func buildFeatureGates(klusterlet *operatorapiv1.Klusterlet, config *klusterletConfig) metav1.Condition {
+	var registrationFeatureGates, workFeatureGates []operatorapiv1.FeatureGate
+	if klusterlet.Spec.RegistrationConfiguration != nil {
+		registrationFeatureGates = klusterlet.Spec.RegistrationConfiguration.FeatureGates
+	}
+	if klusterlet.Spec.WorkConfiguration != nil {
+		workFeatureGates = klusterlet.Spec.WorkConfiguration.FeatureGates
+	}
+
+	var registrationInvalidMsg, workInvalidMsg, addOnInvalidMsg string
+	config.RegistrationFeatureGates, registrationInvalidMsg = helpers.ConvertToFeatureGateFlags(
+		"Registration", registrationFeatureGates, ocmfeature.DefaultSpokeRegistrationFeatureGates)
+	config.WorkFeatureGates, workInvalidMsg = helpers.ConvertToFeatureGateFlags(
+		"Work", workFeatureGates, ocmfeature.DefaultSpokeWorkFeatureGates)
+	config.AddOnFeatureGates, addOnInvalidMsg = helpers.ConvertToFeatureGateFlags(
+		"AddOn", helpers.AddOnFeatureGates(klusterlet), feature.DefaultControlPlaneFeatureGates)
+
+	return helpers.BuildFeatureCondition(registrationInvalidMsg, workInvalidMsg, addOnInvalidMsg)
+}
+
 func ensureNamespace(ctx context.Context, kubeClient kubernetes.Interface, klusterlet *operatorapiv1.Klusterlet, namespace string) error {
 	_, err := kubeClient.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
 	switch {
@@ -305,20 +349,7 @@ func ensureNamespace(ctx context.Context, kubeClient kubernetes.Interface, klust
 
 func getAgentImage(ctx context.Context, kubeClient kubernetes.Interface, klusterlet *operatorapiv1.Klusterlet) (string, error) {
 	if klusterlet.Spec.DeployOption.Mode == operatorapiv1.InstallModeHosted {
-		namespace := helpers.GetComponentNamespace()
-		name := "multicluster-controlplane"
-		deploy, err := kubeClient.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
-		if err != nil {
-			return "", err
-		}
-
-		for _, c := range deploy.Spec.Template.Spec.Containers {
-			if c.Name == "controlplane" {
-				return c.Image, nil
-			}
-		}
-
-		return "", fmt.Errorf("faild to find current controlplane image from `controlplane` container in deployment %s", name)
+		return helpers.GetControlplaneImage(ctx, kubeClient)
 	}
 
 	// if klusterlet is in default mode, the management agent need not to be deployed
@@ -64,6 +64,11 @@ func (r *crdReconcile) reconcile(ctx context.Context, klusterlet *operatorapiv1.
 // by multiple klusterlets. Consequently, the CRs of those CRDs will not be deleted as well when deleting a klusterlet.
 // Only clean the version label on crds, so another klusterlet can update crds later.
 func (r *crdReconcile) clean(ctx context.Context, klusterlet *operatorapiv1.Klusterlet, config klusterletConfig) (*operatorapiv1.Klusterlet, reconcileState, error) {
+	// the crds are kept on the managed cluster when the klusterlet is detached, they are used by the applied resources
+	if helpers.IsKlusterletDetached(klusterlet) {
+		return klusterlet, reconcileContinue, nil
+	}
+
 	crdManager := crdmanager.NewManager[*apiextensionsv1.CustomResourceDefinition](
 		r.managedClusterClients.apiExtensionClient.ApiextensionsV1().CustomResourceDefinitions(),
 		crdmanager.EqualV1,
@@ -92,8 +92,21 @@ func (r *managedReconcile) clean(ctx context.Context, klusterlet *operatorapiv1.
 		return klusterlet, reconcileContinue, nil
 	}
 
-	if err := r.cleanUpAppliedManifestWorks(ctx, klusterlet, config); err != nil {
-		return klusterlet, reconcileStop, err
+	detached := helpers.IsKlusterletDetached(klusterlet)
+	if detached {
+		if err := r.detachAppliedManifestWorks(ctx, klusterlet); err != nil {
+			return klusterlet, reconcileStop, err
+		}
+	} else {
+		if err := r.cleanUpAppliedManifestWorks(ctx, klusterlet, config); err != nil {
+			return klusterlet, reconcileStop, err
+		}
+	}
+
+	// the klusterlet is migrated to another controlplane, its static resources and namespace on the managed cluster
+	// are taken over by the klusterlet of the target controlplane
+	if helpers.IsKlusterletMigratedOut(klusterlet) {
+		return klusterlet, reconcileContinue, nil
 	}
 
 	if err := removeStaticResources(ctx, r.managedClusterClients.kubeClient, r.managedClusterClients.apiExtensionClient,
@@ -103,7 +116,11 @@ func (r *managedReconcile) clean(ctx context.Context, klusterlet *operatorapiv1.
 
 	// remove the klusterlet namespace and klusterlet addon namespace on the managed cluster
 	// For now, whether in Default or Hosted mode, the addons could be deployed on the managed cluster.
+	// The addon namespace is kept when the klusterlet is detached, since the addon workloads are kept.
 	namespaces := []string{config.KlusterletNamespace, fmt.Sprintf("%s-addon", config.KlusterletNamespace)}
+	if detached {
+		namespaces = []string{config.KlusterletNamespace}
+	}
 	for _, namespace := range namespaces {
 		if err := r.managedClusterClients.kubeClient.CoreV1().Namespaces().Delete(
 			ctx, namespace, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
@@ -148,3 +165,48 @@ func (r *managedReconcile) cleanUpAppliedManifestWorks(ctx context.Context, klus
 	}
 	return utilerrors.NewAggregate(errs)
 }
+
+// detachAppliedManifestWorks orphans the AppliedManifestWorks of the klusterlet, their finalizers are removed and
+// they are deleted with the orphan propagation policy, so the owner references of the applied resources are removed
+// by the garbage collector and the applied resources are kept on the managed cluster.
+func (r *managedReconcile) detachAppliedManifestWorks(ctx context.Context, klusterlet *operatorapiv1.Klusterlet) error {
+	appliedManifestWorks, err := r.managedClusterClients.appliedManifestWorkClient.List(ctx, metav1.ListOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to list AppliedManifestWorks: %w", err)
+	}
+
+	orphan := metav1.DeletePropagationOrphan
+	var errs []error
+	for index := range appliedManifestWorks.Items {
+		appliedManifestWork := &appliedManifestWorks.Items[index]
+		// ignore AppliedManifestWork for other klusterlet
+		if string(klusterlet.UID) != appliedManifestWork.Spec.AgentID {
+			continue
+		}
+
+		if mutated := removeFinalizer(appliedManifestWork, appliedManifestWorkFinalizer); mutated {
+			_, err := r.managedClusterClients.appliedManifestWorkClient.Update(ctx, appliedManifestWork, metav1.UpdateOptions{})
+			if errors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("unable to remove finalizer from AppliedManifestWork %q: %w",
+					appliedManifestWork.Name, err))
+				continue
+			}
+		}
+
+		err := r.managedClusterClients.appliedManifestWorkClient.Delete(ctx, appliedManifestWork.Name,
+			metav1.DeleteOptions{PropagationPolicy: &orphan})
+		if err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("unable to orphan AppliedManifestWork %q: %w", appliedManifestWork.Name, err))
+			continue
+		}
+		r.recorder.Eventf("AppliedManifestWorkOrphaned",
+			"the AppliedManifestWork %s is orphaned, its applied resources are kept", appliedManifestWork.Name)
+	}
+	return utilerrors.NewAggregate(errs)
+}
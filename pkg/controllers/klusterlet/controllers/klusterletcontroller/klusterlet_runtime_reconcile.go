@@ -4,6 +4,7 @@ package klusterletcontroller
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/openshift/library-go/pkg/assets"
 	"github.com/openshift/library-go/pkg/operator/events"
@@ -25,6 +26,8 @@ type runtimeReconcile struct {
 	kubeClient            kubernetes.Interface
 	recorder              events.Recorder
 	cache                 resourceapply.ResourceCache
+	// tokenTTL is the lifetime of the token in the managed cluster kubeconfig secret
+	tokenTTL time.Duration
 }
 
 func (r *runtimeReconcile) reconcile(ctx context.Context,
@@ -75,17 +78,8 @@ func (r *runtimeReconcile) createManagedClusterKubeconfig(
 	klusterlet *operatorapiv1.Klusterlet,
 	klusterletNamespace, agentNamespace, saName, secretName string,
 	recorder events.Recorder) error {
-	tokenGetter := helpers.SATokenGetter(ctx, saName, klusterletNamespace, r.managedClusterClients.kubeClient)
-	err := helpers.SyncKubeConfigSecret(
-		ctx,
-		secretName,
-		agentNamespace,
-		"/spoke/config/kubeconfig",
-		r.managedClusterClients.kubeconfig,
-		r.kubeClient.CoreV1(),
-		tokenGetter,
-		recorder,
-	)
+	err := syncManagedClusterKubeconfig(ctx, r.kubeClient, r.managedClusterClients,
+		klusterletNamespace, agentNamespace, saName, secretName, r.tokenTTL, recorder)
 	if err != nil {
 		meta.SetStatusCondition(&klusterlet.Status.Conditions, metav1.Condition{
 			Type: klusterletApplied, Status: metav1.ConditionFalse, Reason: "KlusterletApplyFailed",
@@ -95,6 +89,29 @@ func (r *runtimeReconcile) createManagedClusterKubeconfig(
 	return err
 }
 
+// syncManagedClusterKubeconfig applies the managed cluster kubeconfig secret for the hosted agent, the kubeconfig
+// refers to the token file in the mounted secret, so the agent reloads the refreshed token without a restart.
+func syncManagedClusterKubeconfig(
+	ctx context.Context,
+	kubeClient kubernetes.Interface,
+	managedClusterClients *managedClusterClients,
+	klusterletNamespace, agentNamespace, saName, secretName string,
+	tokenTTL time.Duration,
+	recorder events.Recorder) error {
+	tokenGetter := helpers.SATokenGetter(ctx, saName, klusterletNamespace, managedClusterClients.kubeClient, tokenTTL)
+	return helpers.SyncKubeConfigSecret(
+		ctx,
+		secretName,
+		agentNamespace,
+		"/spoke/config/kubeconfig",
+		managedClusterClients.kubeconfig,
+		kubeClient.CoreV1(),
+		tokenGetter,
+		tokenTTL,
+		recorder,
+	)
+}
+
 func (r *runtimeReconcile) clean(ctx context.Context,
 	klusterlet *operatorapiv1.Klusterlet, config klusterletConfig) (*operatorapiv1.Klusterlet, reconcileState, error) {
 	deployments := []string{fmt.Sprintf("%s-multicluster-controlplane-agent", config.KlusterletName)}
@@ -0,0 +1,190 @@
+// Copyright Contributors to the Open Cluster Management project
+package klusterletcontroller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	operatorv1client "open-cluster-management.io/api/client/operator/clientset/versioned/typed/operator/v1"
+	operatorinformer "open-cluster-management.io/api/client/operator/informers/externalversions/operator/v1"
+	operatorlister "open-cluster-management.io/api/client/operator/listers/operator/v1"
+	workv1client "open-cluster-management.io/api/client/work/clientset/versioned/typed/work/v1"
+	operatorapiv1 "open-cluster-management.io/api/operator/v1"
+
+	"github.com/stolostron/multicluster-controlplane/pkg/controllers/klusterlet/helpers"
+)
+
+// klusterletManagedClusterTokenValid reports the expiry of the token that is used by the hosted agent to access the
+// managed cluster.
+const klusterletManagedClusterTokenValid = "ManagedClusterTokenValid"
+
+// ManagedClusterTokenResyncInterval is exposed so that integration tests can crank up the controller sync speed.
+var ManagedClusterTokenResyncInterval = time.Minute
+
+// managedClusterTokenController refreshes the token of the managed cluster kubeconfig secret of the hosted klusterlets
+// periodically, so the short-lived token is rotated before it expires even if the klusterlet is not changed.
+type managedClusterTokenController struct {
+	kubeClient                kubernetes.Interface
+	controlplaneKubeClient    kubernetes.Interface
+	apiExtensionClient        apiextensionsclient.Interface
+	appliedManifestWorkClient workv1client.AppliedManifestWorkInterface
+	klusterletClient          operatorv1client.KlusterletInterface
+	klusterletLister          operatorlister.KlusterletLister
+	tokenTTL                  time.Duration
+}
+
+// NewManagedClusterTokenController returns a managedClusterTokenController, the tokenTTL is the lifetime of the
+// token in the managed cluster kubeconfig secret.
+func NewManagedClusterTokenController(
+	kubeClient kubernetes.Interface,
+	controlplaneKubeClient kubernetes.Interface,
+	apiExtensionClient apiextensionsclient.Interface,
+	klusterletClient operatorv1client.KlusterletInterface,
+	klusterletInformer operatorinformer.KlusterletInformer,
+	appliedManifestWorkClient workv1client.AppliedManifestWorkInterface,
+	tokenTTL time.Duration,
+	recorder events.Recorder) factory.Controller {
+	controller := &managedClusterTokenController{
+		kubeClient:                kubeClient,
+		controlplaneKubeClient:    controlplaneKubeClient,
+		apiExtensionClient:        apiExtensionClient,
+		appliedManifestWorkClient: appliedManifestWorkClient,
+		klusterletClient:          klusterletClient,
+		klusterletLister:          klusterletInformer.Lister(),
+		tokenTTL:                  tokenTTL,
+	}
+
+	return factory.New().WithSync(controller.sync).
+		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			return accessor.GetName()
+		}, klusterletInformer.Informer()).
+		ResyncEvery(ManagedClusterTokenResyncInterval).
+		ToController("ManagedClusterTokenController", recorder)
+}
+
+func (n *managedClusterTokenController) sync(ctx context.Context, controllerContext factory.SyncContext) error {
+	klusterletName := controllerContext.QueueKey()
+	if klusterletName == "" {
+		return nil
+	}
+
+	// triggered by resync, checking the tokens of all hosted klusterlets
+	if klusterletName == factory.DefaultQueueKey {
+		klusterlets, err := n.klusterletLister.List(labels.Everything())
+		if err != nil {
+			return err
+		}
+
+		for _, klusterlet := range klusterlets {
+			if klusterlet.Spec.DeployOption.Mode == operatorapiv1.InstallModeHosted {
+				controllerContext.Queue().Add(klusterlet.Name)
+			}
+		}
+		return nil
+	}
+
+	klog.V(4).Infof("Reconciling the managed cluster token of klusterlet %q", klusterletName)
+
+	klusterlet, err := n.klusterletLister.Get(klusterletName)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// the managed cluster kubeconfig secret is created by the klusterlet controller after the managed cluster is
+	// ready to apply, the token is not refreshed when the klusterlet is being deleted, migrated or paused, the token
+	// of a paused klusterlet is refreshed by the resync after it is resumed
+	if klusterlet.Spec.DeployOption.Mode != operatorapiv1.InstallModeHosted ||
+		!klusterlet.DeletionTimestamp.IsZero() ||
+		helpers.IsKlusterletMigrating(klusterlet) ||
+		helpers.IsKlusterletPaused(klusterlet) ||
+		!hasFinalizer(klusterlet, klusterletHostedFinalizer) {
+		return nil
+	}
+
+	managedClusterClients, err := newManagedClusterClientsBuilder(
+		klusterlet,
+		n.kubeClient,
+		n.controlplaneKubeClient,
+		n.apiExtensionClient,
+		n.appliedManifestWorkClient,
+	).build(ctx)
+	if err != nil {
+		// the ReadyToApply condition is reported by the klusterlet controller
+		return fmt.Errorf("failed to build managedcluster kube clients, %v", err)
+	}
+
+	agentNamespace := helpers.AgentNamespace(klusterlet)
+	secretName := helpers.ExternalManagedClusterKubeConfigSecret(klusterlet)
+	syncErr := syncManagedClusterKubeconfig(
+		ctx,
+		n.kubeClient,
+		managedClusterClients,
+		helpers.KlusterletNamespace(klusterlet),
+		agentNamespace,
+		fmt.Sprintf("%s-agent-sa", klusterlet.Name),
+		secretName,
+		n.tokenTTL,
+		controllerContext.Recorder(),
+	)
+
+	var expiration time.Time
+	secret, err := n.kubeClient.CoreV1().Secrets(agentNamespace).Get(ctx, secretName, metav1.GetOptions{})
+	if err == nil {
+		expiration, err = helpers.TokenExpiration(secret)
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	cond := tokenCondition(expiration, syncErr)
+	if _, _, err := helpers.UpdateKlusterletStatus(ctx, n.klusterletClient, klusterletName,
+		helpers.UpdateKlusterletConditionFn(cond)); err != nil {
+		return err
+	}
+
+	return syncErr
+}
+
+func tokenCondition(expiration time.Time, syncErr error) metav1.Condition {
+	switch {
+	case syncErr == nil:
+		return metav1.Condition{
+			Type:    klusterletManagedClusterTokenValid,
+			Status:  metav1.ConditionTrue,
+			Reason:  "ManagedClusterTokenValid",
+			Message: fmt.Sprintf("The managed cluster token expires at %s", expiration.UTC().Format(time.RFC3339)),
+		}
+	case expiration.IsZero() || !expiration.After(time.Now()):
+		return metav1.Condition{
+			Type:    klusterletManagedClusterTokenValid,
+			Status:  metav1.ConditionFalse,
+			Reason:  "ManagedClusterTokenExpired",
+			Message: fmt.Sprintf("The managed cluster token is expired and failed to refresh it, %v", syncErr),
+		}
+	default:
+		return metav1.Condition{
+			Type:   klusterletManagedClusterTokenValid,
+			Status: metav1.ConditionTrue,
+			Reason: "ManagedClusterTokenRefreshFailed",
+			Message: fmt.Sprintf("The managed cluster token expires at %s and failed to refresh it, %v",
+				expiration.UTC().Format(time.RFC3339), syncErr),
+		}
+	}
+}
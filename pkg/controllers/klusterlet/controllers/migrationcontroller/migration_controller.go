@@ -0,0 +1,842 @@
+// Copyright Contributors to the Open Cluster Management project
+package migrationcontroller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	clusterinfov1beta1 "github.com/stolostron/cluster-lifecycle-api/clusterinfo/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	clusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	operatorclient "open-cluster-management.io/api/client/operator/clientset/versioned"
+	operatorv1client "open-cluster-management.io/api/client/operator/clientset/versioned/typed/operator/v1"
+	operatorv1informers "open-cluster-management.io/api/client/operator/informers/externalversions/operator/v1"
+	operatorlister "open-cluster-management.io/api/client/operator/listers/operator/v1"
+	workclient "open-cluster-management.io/api/client/work/clientset/versioned"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	operatorapiv1 "open-cluster-management.io/api/operator/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+	propagatorv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+
+	"github.com/stolostron/multicluster-controlplane/pkg/controllers/klusterlet/helpers"
+)
+
+const kubeconfigKey = "kubeconfig"
+
+// MigrationRequeueInterval is exposed so that integration tests can crank up the controller sync speed.
+var MigrationRequeueInterval = 10 * time.Second
+
+// MigrationBootstrapTokenExpiration is the lifetime of the bootstrap token that the agent uses to join the target
+// controlplane.
+var MigrationBootstrapTokenExpiration = 24 * time.Hour
+
+// exportedResources are the resources in the managed cluster namespace that are exported to the target controlplane
+// besides the manifest works, their status is reported by the agent or the controllers of the target controlplane.
+// The replicated policies are not exported, their root policies are exported and propagated by the target
+// controlplane.
+var exportedResources = []schema.GroupVersionResource{
+	clusterinfov1beta1.GroupVersion.WithResource("managedclusterinfos"),
+}
+
+var (
+	policyGVR           = policyv1.GroupVersion.WithResource("policies")
+	placementBindingGVR = policyv1.GroupVersion.WithResource("placementbindings")
+	policySetGVR        = schema.GroupVersionResource{
+		Group: policyv1.GroupVersion.Group, Version: "v1beta1", Resource: "policysets"}
+	placementRuleGVR = schema.GroupVersionResource{
+		Group: "apps.open-cluster-management.io", Version: "v1", Resource: "placementrules"}
+	placementGVR = schema.GroupVersionResource{
+		Group: "cluster.open-cluster-management.io", Version: "v1beta1", Resource: "placements"}
+	managedClusterSetBindingGVR = schema.GroupVersionResource{
+		Group: "cluster.open-cluster-management.io", Version: "v1beta2", Resource: "managedclustersetbindings"}
+	managedClusterSetGVR = schema.GroupVersionResource{
+		Group: "cluster.open-cluster-management.io", Version: "v1beta2", Resource: "managedclustersets"}
+)
+
+// exportedKlusterletAnnotations are the annotations of the klusterlet that are kept on the target controlplane.
+var exportedKlusterletAnnotations = []string{
+	helpers.AddOnFeatureGatesAnnotation,
+}
+
+// targetClients are the clients of the target controlplane
+type targetClients struct {
+	server           string
+	caData           []byte
+	kubeClient       kubernetes.Interface
+	dynamicClient    dynamic.Interface
+	clusterClient    clusterclient.Interface
+	workClient       workclient.Interface
+	klusterletClient operatorv1client.KlusterletInterface
+}
+
+// migrationController migrates a hosted klusterlet to another controlplane, the migration has three phases
+//   - exporting, the managed cluster and its manifest works, managed cluster info and the root policies of its
+//     policies are exported to the target controlplane, and a klusterlet is created on the target controlplane, the
+//     target klusterlet is not reconciled until the migration is completed.
+//   - rebootstrapping, the bootstrap secret of the agent is rewritten with a bootstrap token of the target
+//     controlplane, the agent is reloaded by the bootstrap controller and it joins the target controlplane.
+//   - detaching, the target klusterlet takes over the managed cluster, once the agent of the target klusterlet is
+//     available, the klusterlet is deleted with the detach mode, so the workloads on the managed cluster are kept, and
+//     the rebootstrapped agent and its secrets are removed from the management cluster.
+//
+// The progress is reported with the Migrated condition of the klusterlets on both controlplanes.
+type migrationController struct {
+	kubeClient                kubernetes.Interface
+	controlplaneKubeClient    kubernetes.Interface
+	controlplaneDynamicClient dynamic.Interface
+	controlplaneClusterClient clusterclient.Interface
+	controlplaneWorkClient    workclient.Interface
+	klusterletClient          operatorv1client.KlusterletInterface
+	klusterletLister          operatorlister.KlusterletLister
+	recorder                  events.Recorder
+}
+
+// NewMigrationController returns a migrationController, the kubeClient is for the management cluster and the
+// controlplane clients are for the source controlplane.
+func NewMigrationController(
+	kubeClient kubernetes.Interface,
+	controlplaneKubeClient kubernetes.Interface,
+	controlplaneDynamicClient dynamic.Interface,
+	controlplaneClusterClient clusterclient.Interface,
+	controlplaneWorkClient workclient.Interface,
+	klusterletClient operatorv1client.KlusterletInterface,
+	klusterletInformer operatorv1informers.KlusterletInformer,
+	recorder events.Recorder) factory.Controller {
+	controller := &migrationController{
+		kubeClient:                kubeClient,
+		controlplaneKubeClient:    controlplaneKubeClient,
+		controlplaneDynamicClient: controlplaneDynamicClient,
+		controlplaneClusterClient: controlplaneClusterClient,
+		controlplaneWorkClient:    controlplaneWorkClient,
+		klusterletClient:          klusterletClient,
+		klusterletLister:          klusterletInformer.Lister(),
+		recorder:                  recorder,
+	}
+	return factory.New().WithSync(controller.sync).
+		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			if _, ok := accessor.GetAnnotations()[helpers.MigrationTargetSecretAnnotation]; !ok {
+				return ""
+			}
+			return accessor.GetName()
+		}, klusterletInformer.Informer()).
+		ToController("KlusterletMigrationController", recorder)
+}
+
+func (c *migrationController) sync(ctx context.Context, controllerContext factory.SyncContext) error {
+	klusterletName := controllerContext.QueueKey()
+	if klusterletName == "" || klusterletName == factory.DefaultQueueKey {
+		return nil
+	}
+
+	klog.V(4).Infof("Reconciling the migration of klusterlet %q", klusterletName)
+
+	klusterlet, err := c.klusterletLister.Get(klusterletName)
+	switch {
+	case errors.IsNotFound(err):
+		return nil
+	case err != nil:
+		return err
+	}
+
+	targetSecretName, ok := klusterlet.Annotations[helpers.MigrationTargetSecretAnnotation]
+	if !ok || !klusterlet.DeletionTimestamp.IsZero() || helpers.IsKlusterletPaused(klusterlet) {
+		return nil
+	}
+
+	if klusterlet.Spec.DeployOption.Mode != operatorapiv1.InstallModeHosted {
+		return c.updateMigratedCondition(ctx, klusterletName, metav1.ConditionFalse, helpers.KlusterletMigrationFailed,
+			"Only the klusterlet in the hosted mode can be migrated")
+	}
+
+	phase := helpers.KlusterletMigrationExporting
+	if cond := meta.FindStatusCondition(klusterlet.Status.Conditions, helpers.KlusterletMigrated); cond != nil &&
+		cond.Reason != helpers.KlusterletMigrationFailed {
+		phase = cond.Reason
+	}
+
+	if phase == helpers.KlusterletMigrationDetaching {
+		// the klusterlet is being deleted by the cleanup controller
+		return nil
+	}
+
+	clusterName := helpers.ClusterName(klusterlet)
+	target, err := c.buildTargetClients(ctx, clusterName, targetSecretName)
+	if err != nil {
+		return c.migrationFailed(ctx, klusterletName, fmt.Errorf("failed to build the target controlplane clients, %v", err))
+	}
+
+	switch phase {
+	case helpers.KlusterletMigrationExporting:
+		if err := c.updateMigratedCondition(ctx, klusterletName, metav1.ConditionFalse,
+			helpers.KlusterletMigrationExporting, "Exporting the managed cluster to the target controlplane"); err != nil {
+			return err
+		}
+
+		if err := c.export(ctx, klusterlet, target); err != nil {
+			return c.migrationFailed(ctx, klusterletName, fmt.Errorf("failed to export the managed cluster, %v", err))
+		}
+
+		if err := c.rebootstrap(ctx, klusterlet, target); err != nil {
+			return c.migrationFailed(ctx, klusterletName, fmt.Errorf("failed to rebootstrap the agent, %v", err))
+		}
+
+		c.recorder.Eventf("KlusterletRebootstrapped",
+			"The agent of klusterlet %s is rebootstrapped to the target controlplane", klusterletName)
+		controllerContext.Queue().AddAfter(klusterletName, MigrationRequeueInterval)
+		return c.updateMigratedCondition(ctx, klusterletName, metav1.ConditionFalse,
+			helpers.KlusterletMigrationRebootstrapping, "Waiting for the managed cluster to join the target controlplane")
+	case helpers.KlusterletMigrationRebootstrapping:
+		managedCluster, err := target.clusterClient.ClusterV1().ManagedClusters().Get(ctx, clusterName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		if !meta.IsStatusConditionTrue(managedCluster.Status.Conditions, clusterv1.ManagedClusterConditionJoined) {
+			controllerContext.Queue().AddAfter(klusterletName, MigrationRequeueInterval)
+			return nil
+		}
+
+		if err := c.completeTargetKlusterlet(ctx, klusterlet.Name, target); err != nil {
+			return err
+		}
+
+		// the target controlplane deploys its own agent after the migration is completed, the rebootstrapped agent
+		// is kept until the agent of the target controlplane is available, so the managed cluster is always managed
+		targetKlusterlet, err := target.klusterletClient.Get(ctx, klusterlet.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		if !meta.IsStatusConditionTrue(targetKlusterlet.Status.Conditions, helpers.KlusterletAvailable) {
+			controllerContext.Queue().AddAfter(klusterletName, MigrationRequeueInterval)
+			return c.updateMigratedCondition(ctx, klusterletName, metav1.ConditionFalse,
+				helpers.KlusterletMigrationRebootstrapping, "Waiting for the agent of the target controlplane to be available")
+		}
+
+		if err := c.updateMigratedCondition(ctx, klusterletName, metav1.ConditionFalse,
+			helpers.KlusterletMigrationDetaching,
+			"The managed cluster is joined to the target controlplane, detaching the klusterlet"); err != nil {
+			return err
+		}
+
+		return c.detach(ctx, klusterletName)
+	}
+
+	return nil
+}
+
+// export copies the managed cluster and its resources to the target controlplane, the existing resources on the
+// target controlplane are overwritten, so the export can be retried.
+func (c *migrationController) export(ctx context.Context, klusterlet *operatorapiv1.Klusterlet, target *targetClients) error {
+	clusterName := helpers.ClusterName(klusterlet)
+
+	_, err := target.kubeClient.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: clusterName},
+	}, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	if err := c.exportManagedCluster(ctx, clusterName, target); err != nil {
+		return err
+	}
+
+	if err := c.exportManifestWorks(ctx, clusterName, target); err != nil {
+		return err
+	}
+
+	for _, gvr := range exportedResources {
+		if err := c.exportResources(ctx, gvr, clusterName, target); err != nil {
+			return err
+		}
+	}
+
+	if err := c.exportPolicies(ctx, clusterName, target); err != nil {
+		return err
+	}
+
+	// the target klusterlet uses the same managed cluster kubeconfig to manage the managed cluster
+	managedClusterKubeConfig, err := c.controlplaneKubeClient.CoreV1().Secrets(clusterName).Get(
+		ctx, helpers.ManagedClusterKubeConfig, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if err := applySecret(ctx, target.kubeClient, clusterName, helpers.ManagedClusterKubeConfig,
+		managedClusterKubeConfig.Data); err != nil {
+		return err
+	}
+
+	return c.exportKlusterlet(ctx, klusterlet, target)
+}
+
+func (c *migrationController) exportManagedCluster(ctx context.Context, clusterName string, target *targetClients) error {
+	managedCluster, err := c.controlplaneClusterClient.ClusterV1().ManagedClusters().Get(ctx, clusterName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	targetCluster, err := target.clusterClient.ClusterV1().ManagedClusters().Get(ctx, clusterName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		_, err = target.clusterClient.ClusterV1().ManagedClusters().Create(ctx, &clusterv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: clusterName, Labels: managedCluster.Labels},
+			Spec:       clusterv1.ManagedClusterSpec{HubAcceptsClient: true},
+		}, metav1.CreateOptions{})
+		return err
+	case err != nil:
+		return err
+	}
+
+	modified := !targetCluster.Spec.HubAcceptsClient
+	targetCluster = targetCluster.DeepCopy()
+	targetCluster.Spec.HubAcceptsClient = true
+	if targetCluster.Labels == nil {
+		targetCluster.Labels = map[string]string{}
+	}
+	for key, value := range managedCluster.Labels {
+		if targetCluster.Labels[key] != value {
+			targetCluster.Labels[key] = value
+			modified = true
+		}
+	}
+	if !modified {
+		return nil
+	}
+
+	_, err = target.clusterClient.ClusterV1().ManagedClusters().Update(ctx, targetCluster, metav1.UpdateOptions{})
+	return err
+}
+
+func (c *migrationController) exportManifestWorks(ctx context.Context, clusterName string, target *targetClients) error {
+	works, err := c.controlplaneWorkClient.WorkV1().ManifestWorks(clusterName).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	for _, work := range works.Items {
+		targetWork, err := target.workClient.WorkV1().ManifestWorks(clusterName).Get(ctx, work.Name, metav1.GetOptions{})
+		switch {
+		case errors.IsNotFound(err):
+			_, err = target.workClient.WorkV1().ManifestWorks(clusterName).Create(ctx, &workv1.ManifestWork{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        work.Name,
+					Namespace:   clusterName,
+					Labels:      work.Labels,
+					Annotations: work.Annotations,
+				},
+				Spec: work.Spec,
+			}, metav1.CreateOptions{})
+			if err != nil {
+				return err
+			}
+			continue
+		case err != nil:
+			return err
+		}
+
+		if reflect.DeepEqual(targetWork.Spec, work.Spec) &&
+			reflect.DeepEqual(targetWork.Labels, work.Labels) &&
+			reflect.DeepEqual(targetWork.Annotations, work.Annotations) {
+			continue
+		}
+
+		targetWork = targetWork.DeepCopy()
+		targetWork.Labels = work.Labels
+		targetWork.Annotations = work.Annotations
+		targetWork.Spec = work.Spec
+		if _, err := target.workClient.WorkV1().ManifestWorks(clusterName).Update(
+			ctx, targetWork, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// exportResources copies the given resources in the managed cluster namespace, the resources are ignored if they are
+// not installed on the controlplane.
+func (c *migrationController) exportResources(ctx context.Context,
+	gvr schema.GroupVersionResource, clusterName string, target *targetClients) error {
+	objs, err := c.controlplaneDynamicClient.Resource(gvr).Namespace(clusterName).List(ctx, metav1.ListOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for i := range objs.Items {
+		if err := exportObject(ctx, gvr, &objs.Items[i], target); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// exportPolicies copies the root policies of the replicated policies in the managed cluster namespace, and the
+// placement bindings, placements and policy sets that bind the root policies, so the target controlplane propagates
+// the policies to the managed cluster.
+func (c *migrationController) exportPolicies(ctx context.Context, clusterName string, target *targetClients) error {
+	replicatedPolicies, err := c.controlplaneDynamicClient.Resource(policyGVR).Namespace(clusterName).List(
+		ctx, metav1.ListOptions{LabelSelector: common.RootPolicyLabel})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	exported := sets.Set[string]{}
+	export := func(gvr schema.GroupVersionResource, namespace, name string) error {
+		key := fmt.Sprintf("%s/%s/%s", gvr.String(), namespace, name)
+		if exported.Has(key) {
+			return nil
+		}
+		exported.Insert(key)
+
+		obj, err := resourceInterface(c.controlplaneDynamicClient, gvr, namespace).Get(ctx, name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(namespace) != 0 {
+			_, err := target.kubeClient.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: namespace},
+			}, metav1.CreateOptions{})
+			if err != nil && !errors.IsAlreadyExists(err) {
+				return err
+			}
+		}
+		return exportObject(ctx, gvr, obj, target)
+	}
+
+	for _, replicatedPolicy := range replicatedPolicies.Items {
+		// the root policy label is <namespace>.<name>, the namespace does not contain the dot
+		rootPolicy := strings.SplitN(replicatedPolicy.GetLabels()[common.RootPolicyLabel], ".", 2)
+		if len(rootPolicy) != 2 {
+			continue
+		}
+		namespace, name := rootPolicy[0], rootPolicy[1]
+
+		if err := export(policyGVR, namespace, name); err != nil {
+			return err
+		}
+
+		bindings, err := c.controlplaneDynamicClient.Resource(placementBindingGVR).Namespace(namespace).List(
+			ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+
+		for _, obj := range bindings.Items {
+			binding := &propagatorv1.PlacementBinding{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, binding); err != nil {
+				return err
+			}
+
+			bound, err := c.bindsPolicy(ctx, binding, name)
+			if err != nil {
+				return err
+			}
+			if !bound {
+				continue
+			}
+
+			for _, subject := range binding.Subjects {
+				if subject.Kind == "PolicySet" {
+					if err := export(policySetGVR, namespace, subject.Name); err != nil {
+						return err
+					}
+				}
+			}
+
+			switch binding.PlacementRef.Kind {
+			case "PlacementRule":
+				if err := export(placementRuleGVR, namespace, binding.PlacementRef.Name); err != nil {
+					return err
+				}
+			case "Placement":
+				if err := export(placementGVR, namespace, binding.PlacementRef.Name); err != nil {
+					return err
+				}
+
+				// the placement only selects the managed clusters in the cluster sets that are bound to its namespace
+				setBindings, err := c.controlplaneDynamicClient.Resource(managedClusterSetBindingGVR).Namespace(
+					namespace).List(ctx, metav1.ListOptions{})
+				if err != nil {
+					return err
+				}
+				for _, setBinding := range setBindings.Items {
+					if err := export(managedClusterSetGVR, "", setBinding.GetName()); err != nil {
+						return err
+					}
+					if err := export(managedClusterSetBindingGVR, namespace, setBinding.GetName()); err != nil {
+						return err
+					}
+				}
+			}
+
+			if err := export(placementBindingGVR, namespace, binding.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// bindsPolicy returns true if the policy is a subject of the placement binding or it is in a policy set of the
+// subjects.
+func (c *migrationController) bindsPolicy(ctx context.Context,
+	binding *propagatorv1.PlacementBinding, policyName string) (bool, error) {
+	for _, subject := range binding.Subjects {
+		switch subject.Kind {
+		case "Policy":
+			if subject.Name == policyName {
+				return true, nil
+			}
+		case "PolicySet":
+			policySet, err := c.controlplaneDynamicClient.Resource(policySetGVR).Namespace(binding.Namespace).Get(
+				ctx, subject.Name, metav1.GetOptions{})
+			if errors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return false, err
+			}
+
+			policies, _, err := unstructured.NestedStringSlice(policySet.Object, "spec", "policies")
+			if err != nil {
+				return false, err
+			}
+			for _, policy := range policies {
+				if policy == policyName {
+					return true, nil
+				}
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// exportObject copies the object to the target controlplane except its status, the object on the target
+// controlplane is overwritten.
+func exportObject(ctx context.Context,
+	gvr schema.GroupVersionResource, obj *unstructured.Unstructured, target *targetClients) error {
+	content := map[string]interface{}{}
+	for key, value := range obj.Object {
+		if key == "metadata" || key == "status" {
+			continue
+		}
+		content[key] = runtime.DeepCopyJSONValue(value)
+	}
+
+	resource := resourceInterface(target.dynamicClient, gvr, obj.GetNamespace())
+	targetObj, err := resource.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		required := &unstructured.Unstructured{Object: content}
+		required.SetName(obj.GetName())
+		required.SetNamespace(obj.GetNamespace())
+		required.SetLabels(obj.GetLabels())
+		required.SetAnnotations(obj.GetAnnotations())
+		_, err := resource.Create(ctx, required, metav1.CreateOptions{})
+		return err
+	case err != nil:
+		return err
+	}
+
+	modified := !reflect.DeepEqual(targetObj.GetLabels(), obj.GetLabels()) ||
+		!reflect.DeepEqual(targetObj.GetAnnotations(), obj.GetAnnotations())
+	for key, value := range content {
+		if !reflect.DeepEqual(targetObj.Object[key], value) {
+			modified = true
+		}
+	}
+	if !modified {
+		return nil
+	}
+
+	targetObj = targetObj.DeepCopy()
+	targetObj.SetLabels(obj.GetLabels())
+	targetObj.SetAnnotations(obj.GetAnnotations())
+	for key, value := range content {
+		targetObj.Object[key] = value
+	}
+	_, err = resource.Update(ctx, targetObj, metav1.UpdateOptions{})
+	return err
+}
+
+func resourceInterface(client dynamic.Interface,
+	gvr schema.GroupVersionResource, namespace string) dynamic.ResourceInterface {
+	if len(namespace) == 0 {
+		return client.Resource(gvr)
+	}
+	return client.Resource(gvr).Namespace(namespace)
+}
+
+// exportKlusterlet creates the klusterlet on the target controlplane, the klusterlet is marked as migrating, so the
+// target controlplane does not deploy another agent before the migration is completed.
+func (c *migrationController) exportKlusterlet(ctx context.Context,
+	klusterlet *operatorapiv1.Klusterlet, target *targetClients) error {
+	targetKlusterlet, err := target.klusterletClient.Get(ctx, klusterlet.Name, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		annotations := map[string]string{helpers.MigratingFromAnnotation: string(klusterlet.UID)}
+		for _, key := range exportedKlusterletAnnotations {
+			if value, ok := klusterlet.Annotations[key]; ok {
+				annotations[key] = value
+			}
+		}
+
+		if _, err := target.klusterletClient.Create(ctx, &operatorapiv1.Klusterlet{
+			ObjectMeta: metav1.ObjectMeta{Name: klusterlet.Name, Annotations: annotations},
+			Spec:       *klusterlet.Spec.DeepCopy(),
+		}, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case targetKlusterlet.Annotations[helpers.MigratingFromAnnotation] != string(klusterlet.UID):
+		return fmt.Errorf("the klusterlet %s already exists on the target controlplane", klusterlet.Name)
+	}
+
+	_, _, err = helpers.UpdateKlusterletStatus(ctx, target.klusterletClient, klusterlet.Name,
+		helpers.UpdateKlusterletConditionFn(metav1.Condition{
+			Type:    helpers.KlusterletMigrated,
+			Status:  metav1.ConditionFalse,
+			Reason:  helpers.KlusterletMigrationImporting,
+			Message: "The managed cluster is being migrated from another controlplane",
+		}))
+	return err
+}
+
+// rebootstrap rewrites the bootstrap secret of the agent with a bootstrap token of the target controlplane, the token
+// is only allowed to bootstrap the managed cluster and access the resources of the agent addons. The bootstrap
+// controller reloads the agent once it finds the bootstrap secret is changed.
+func (c *migrationController) rebootstrap(ctx context.Context,
+	klusterlet *operatorapiv1.Klusterlet, target *targetClients) error {
+	clusterName := helpers.ClusterName(klusterlet)
+	if err := helpers.EnsureBootstrapServiceAccount(ctx, target.kubeClient, clusterName, c.recorder); err != nil {
+		return err
+	}
+
+	bootstrapKubeConfig, _, err := helpers.BuildBootstrapKubeConfig(ctx, target.kubeClient, clusterName,
+		target.server, target.caData, MigrationBootstrapTokenExpiration)
+	if err != nil {
+		return err
+	}
+
+	secretName := fmt.Sprintf("%s-migration-bootstrap-kubeconfig", klusterlet.Name)
+	if err := applySecret(ctx, c.kubeClient, helpers.AgentNamespace(klusterlet), secretName,
+		bootstrapKubeConfig); err != nil {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		klusterlet, err := c.klusterletClient.Get(ctx, klusterlet.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		if klusterlet.Annotations[helpers.BootstrapKubeConfigSecretAnnotation] == secretName {
+			return nil
+		}
+
+		klusterlet = klusterlet.DeepCopy()
+		if klusterlet.Annotations == nil {
+			klusterlet.Annotations = map[string]string{}
+		}
+		klusterlet.Annotations[helpers.BootstrapKubeConfigSecretAnnotation] = secretName
+		_, err = c.klusterletClient.Update(ctx, klusterlet, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// completeTargetKlusterlet hands over the managed cluster to the target klusterlet
+func (c *migrationController) completeTargetKlusterlet(ctx context.Context, name string, target *targetClients) error {
+	if err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		klusterlet, err := target.klusterletClient.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		if !helpers.IsKlusterletMigrating(klusterlet) {
+			return nil
+		}
+
+		klusterlet = klusterlet.DeepCopy()
+		delete(klusterlet.Annotations, helpers.MigratingFromAnnotation)
+		_, err = target.klusterletClient.Update(ctx, klusterlet, metav1.UpdateOptions{})
+		return err
+	}); err != nil {
+		return err
+	}
+
+	_, _, err := helpers.UpdateKlusterletStatus(ctx, target.klusterletClient, name,
+		helpers.UpdateKlusterletConditionFn(metav1.Condition{
+			Type:    helpers.KlusterletMigrated,
+			Status:  metav1.ConditionTrue,
+			Reason:  helpers.KlusterletMigrationCompleted,
+			Message: "The managed cluster is migrated from another controlplane",
+		}))
+	return err
+}
+
+// detach deletes the klusterlet with the detach mode, the agent and the records of the managed cluster are removed
+// from the source controlplane and its management cluster, and the workloads on the managed cluster are kept.
+func (c *migrationController) detach(ctx context.Context, name string) error {
+	if err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		klusterlet, err := c.klusterletClient.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		if helpers.IsKlusterletDetached(klusterlet) {
+			return nil
+		}
+
+		klusterlet = klusterlet.DeepCopy()
+		if klusterlet.Annotations == nil {
+			klusterlet.Annotations = map[string]string{}
+		}
+		klusterlet.Annotations[helpers.KlusterletDeletionModeAnnotation] = helpers.KlusterletDeletionModeDetach
+		_, err = c.klusterletClient.Update(ctx, klusterlet, metav1.UpdateOptions{})
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if err := c.klusterletClient.Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	c.recorder.Eventf("KlusterletMigrated", "The klusterlet %s is migrated to the target controlplane", name)
+	return nil
+}
+
+func (c *migrationController) buildTargetClients(ctx context.Context,
+	clusterName, secretName string) (*targetClients, error) {
+	secret, err := c.controlplaneKubeClient.CoreV1().Secrets(clusterName).Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	kubeconfig, ok := secret.Data[kubeconfigKey]
+	if !ok {
+		return nil, fmt.Errorf("the secret %s/%s does not have the %s", clusterName, secretName, kubeconfigKey)
+	}
+
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// the ca of the target controlplane is used in the bootstrap kubeconfig of the agent
+	if err := rest.LoadTLSFiles(config); err != nil {
+		return nil, err
+	}
+
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	clusterClient, err := clusterclient.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	workClient, err := workclient.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	operatorClient, err := operatorclient.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &targetClients{
+		server:           config.Host,
+		caData:           config.CAData,
+		kubeClient:       kubeClient,
+		dynamicClient:    dynamicClient,
+		clusterClient:    clusterClient,
+		workClient:       workClient,
+		klusterletClient: operatorClient.OperatorV1().Klusterlets(),
+	}, nil
+}
+
+func (c *migrationController) migrationFailed(ctx context.Context, klusterletName string, err error) error {
+	if updateErr := c.updateMigratedCondition(ctx, klusterletName, metav1.ConditionFalse,
+		helpers.KlusterletMigrationFailed, err.Error()); updateErr != nil {
+		return updateErr
+	}
+	return err
+}
+
+func (c *migrationController) updateMigratedCondition(ctx context.Context, klusterletName string,
+	status metav1.ConditionStatus, reason, message string) error {
+	_, _, err := helpers.UpdateKlusterletStatus(ctx, c.klusterletClient, klusterletName,
+		helpers.UpdateKlusterletConditionFn(metav1.Condition{
+			Type:    helpers.KlusterletMigrated,
+			Status:  status,
+			Reason:  reason,
+			Message: message,
+		}))
+	return err
+}
+
+func applySecret(ctx context.Context, kubeClient kubernetes.Interface,
+	namespace, name string, data map[string][]byte) error {
+	secret, err := kubeClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		_, err = kubeClient.CoreV1().Secrets(namespace).Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Data:       data,
+		}, metav1.CreateOptions{})
+		return err
+	case err != nil:
+		return err
+	}
+
+	if reflect.DeepEqual(secret.Data, data) {
+		return nil
+	}
+
+	secret = secret.DeepCopy()
+	secret.Data = data
+	_, err = kubeClient.CoreV1().Secrets(namespace).Update(ctx, secret, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,204 @@
+// Copyright Contributors to the Open Cluster Management project
+package pausecontroller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	operatorv1client "open-cluster-management.io/api/client/operator/clientset/versioned/typed/operator/v1"
+	operatorv1informers "open-cluster-management.io/api/client/operator/informers/externalversions/operator/v1"
+	operatorlister "open-cluster-management.io/api/client/operator/listers/operator/v1"
+	operatorapiv1 "open-cluster-management.io/api/operator/v1"
+	propagatorv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+
+	"github.com/stolostron/multicluster-controlplane/pkg/controllers/klusterlet/helpers"
+)
+
+var policyGVR = propagatorv1.GroupVersion.WithResource("policies")
+
+// pauseController reports the Paused condition of the klusterlets, the other klusterlet controllers stop reconciling
+// a paused klusterlet. When a hosted klusterlet is in its maintenance window, the agent is scaled down, and it is
+// scaled up by the klusterlet controller after the maintenance window. The compliance of the replicated policies of a
+// scaled down agent is set to Pending, since it is not reported by the agent, the root policies report the cluster as
+// Pending until the agent reports the compliance again after it is scaled up.
+type pauseController struct {
+	kubeClient                kubernetes.Interface
+	controlplaneDynamicClient dynamic.Interface
+	klusterletClient          operatorv1client.KlusterletInterface
+	klusterletLister          operatorlister.KlusterletLister
+	recorder                  events.Recorder
+}
+
+// NewPauseController returns a pauseController, the kubeClient is for the management cluster.
+func NewPauseController(
+	kubeClient kubernetes.Interface,
+	controlplaneDynamicClient dynamic.Interface,
+	klusterletClient operatorv1client.KlusterletInterface,
+	klusterletInformer operatorv1informers.KlusterletInformer,
+	recorder events.Recorder) factory.Controller {
+	controller := &pauseController{
+		kubeClient:                kubeClient,
+		controlplaneDynamicClient: controlplaneDynamicClient,
+		klusterletClient:          klusterletClient,
+		klusterletLister:          klusterletInformer.Lister(),
+		recorder:                  recorder,
+	}
+	return factory.New().WithSync(controller.sync).
+		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			return accessor.GetName()
+		}, klusterletInformer.Informer()).
+		ToController("KlusterletPauseController", recorder)
+}
+
+func (c *pauseController) sync(ctx context.Context, controllerContext factory.SyncContext) error {
+	klusterletName := controllerContext.QueueKey()
+	if klusterletName == "" || klusterletName == factory.DefaultQueueKey {
+		return nil
+	}
+
+	klog.V(4).Infof("Reconciling the pause of klusterlet %q", klusterletName)
+
+	klusterlet, err := c.klusterletLister.Get(klusterletName)
+	switch {
+	case errors.IsNotFound(err):
+		return nil
+	case err != nil:
+		return err
+	}
+
+	now := time.Now()
+	start, end, hasWindow, windowErr := helpers.KlusterletMaintenanceWindow(klusterlet)
+	inWindow := hasWindow && windowErr == nil && !now.Before(start) && now.Before(end)
+
+	if !helpers.IsKlusterletPaused(klusterlet) {
+		if hasWindow && windowErr == nil && now.Before(start) {
+			// pause the klusterlet once the maintenance window starts
+			controllerContext.Queue().AddAfter(klusterletName, start.Sub(now))
+		}
+
+		cond := meta.FindStatusCondition(klusterlet.Status.Conditions, helpers.KlusterletPaused)
+		if cond != nil && cond.Status == metav1.ConditionTrue {
+			c.recorder.Eventf("KlusterletResumed", "The klusterlet %s is resumed", klusterletName)
+		}
+
+		switch {
+		case windowErr != nil:
+			return c.updatePausedCondition(ctx, klusterletName, metav1.ConditionFalse, "InvalidMaintenanceWindow",
+				windowErr.Error())
+		case cond != nil:
+			return c.updatePausedCondition(ctx, klusterletName, metav1.ConditionFalse, "KlusterletNotPaused",
+				"The klusterlet is not paused")
+		}
+		return nil
+	}
+
+	reason, message := "KlusterletPaused", "The klusterlet is paused"
+	if inWindow {
+		reason = "KlusterletInMaintenanceWindow"
+		message = fmt.Sprintf("The klusterlet is in the maintenance window until %s", end.UTC().Format(time.RFC3339))
+
+		if klusterlet.Spec.DeployOption.Mode == operatorapiv1.InstallModeHosted {
+			if err := c.scaleDownAgent(ctx, klusterlet); err != nil {
+				return err
+			}
+			if err := c.markPoliciesPending(ctx, helpers.ClusterName(klusterlet)); err != nil {
+				return err
+			}
+			message += ", the agent is scaled down"
+		}
+
+		// resume the klusterlet once the maintenance window ends
+		controllerContext.Queue().AddAfter(klusterletName, end.Sub(now))
+	}
+
+	return c.updatePausedCondition(ctx, klusterletName, metav1.ConditionTrue, reason, message)
+}
+
+func (c *pauseController) scaleDownAgent(ctx context.Context, klusterlet *operatorapiv1.Klusterlet) error {
+	namespace := helpers.AgentNamespace(klusterlet)
+	name := fmt.Sprintf("%s-multicluster-controlplane-agent", klusterlet.Name)
+
+	scale, err := c.kubeClient.AppsV1().Deployments(namespace).GetScale(ctx, name, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		return nil
+	case err != nil:
+		return err
+	}
+
+	if scale.Spec.Replicas == 0 {
+		return nil
+	}
+
+	scale = scale.DeepCopy()
+	scale.Spec.Replicas = 0
+	if _, err := c.kubeClient.AppsV1().Deployments(namespace).UpdateScale(ctx, name, scale, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+
+	c.recorder.Eventf("KlusterletAgentScaledDown", "The agent %s/%s is scaled down in the maintenance window",
+		namespace, name)
+	return nil
+}
+
+// markPoliciesPending sets the compliance of the replicated policies in the managed cluster namespace to Pending, the
+// policies are ignored if the policy addon is not enabled.
+func (c *pauseController) markPoliciesPending(ctx context.Context, clusterName string) error {
+	policies, err := c.controlplaneDynamicClient.Resource(policyGVR).Namespace(clusterName).List(ctx, metav1.ListOptions{})
+	if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	patch := fmt.Sprintf(`{"status":{"compliant":%q}}`, propagatorv1.Pending)
+
+	errs := []error{}
+	for _, policy := range policies.Items {
+		if _, ok := policy.GetLabels()[common.RootPolicyLabel]; !ok {
+			continue
+		}
+
+		compliant, _, _ := unstructured.NestedString(policy.Object, "status", "compliant")
+		if compliant == string(propagatorv1.Pending) {
+			continue
+		}
+
+		if _, err := c.controlplaneDynamicClient.Resource(policyGVR).Namespace(clusterName).Patch(
+			ctx, policy.GetName(), types.MergePatchType, []byte(patch), metav1.PatchOptions{}, "status"); err != nil &&
+			!errors.IsNotFound(err) {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func (c *pauseController) updatePausedCondition(ctx context.Context, klusterletName string,
+	status metav1.ConditionStatus, reason, message string) error {
+	_, _, err := helpers.UpdateKlusterletStatus(ctx, c.klusterletClient, klusterletName,
+		helpers.UpdateKlusterletConditionFn(metav1.Condition{
+			Type:    helpers.KlusterletPaused,
+			Status:  status,
+			Reason:  reason,
+			Message: message,
+		}))
+	return err
+}
@@ -0,0 +1,200 @@
+package ssarcontroller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// SSARWorkers is the maximum number of the SelfSubjectAccessReviews that are created concurrently by the controller.
+var SSARWorkers = 10
+
+// SSARCacheTTL is the time that a review result is cached, the credentials are reviewed again after it, so the
+// revoked credentials are not reported as functional forever.
+var SSARCacheTTL = 10 * time.Minute
+
+// accessReviewKey identifies the cached result of a set of reviews with the credential in a secret.
+type accessReviewKey struct {
+	clusterName     string
+	reviewSet       string
+	secretNamespace string
+	secretName      string
+}
+
+// accessReviewResult is the result of a set of SelfSubjectAccessReviews, the failedReview is the first review that
+// is not allowed.
+type accessReviewResult struct {
+	allowed      bool
+	failedReview *authorizationv1.SelfSubjectAccessReview
+	// secretHash is the hash of the secret content that is reviewed
+	secretHash string
+	expiration time.Time
+}
+
+// accessReviewer creates the SelfSubjectAccessReviews with a bounded worker pool that is shared by all klusterlets,
+// the review results are cached by the secret, so the unchanged credentials are not reviewed again until the secret
+// is changed, the result is expired or the result is invalidated by the RBAC changes of the cluster.
+type accessReviewer struct {
+	workers chan struct{}
+	ttl     time.Duration
+
+	sync.RWMutex
+	// generation is increased once the results are invalidated, the results of the reviews that are started before
+	// the invalidation are not cached.
+	generation uint64
+	results    map[accessReviewKey]accessReviewResult
+}
+
+func newAccessReviewer(workers int, ttl time.Duration) *accessReviewer {
+	return &accessReviewer{
+		workers: make(chan struct{}, workers),
+		ttl:     ttl,
+		results: map[accessReviewKey]accessReviewResult{},
+	}
+}
+
+// review returns the result of the reviews with the credential in the secret for the cluster, the reviewSet
+// identifies the reviews. The client is built only if the result is not cached, the errors are not cached.
+func (r *accessReviewer) review(
+	ctx context.Context,
+	clusterName string,
+	secret *corev1.Secret,
+	reviewSet string,
+	reviews []authorizationv1.SelfSubjectAccessReview,
+	buildClient func() (kubernetes.Interface, error)) (bool, *authorizationv1.SelfSubjectAccessReview, error) {
+	key := accessReviewKey{
+		clusterName:     clusterName,
+		reviewSet:       reviewSet,
+		secretNamespace: secret.Namespace,
+		secretName:      secret.Name,
+	}
+	secretHash := hashSecretData(secret)
+
+	r.RLock()
+	result, ok := r.results[key]
+	generation := r.generation
+	r.RUnlock()
+	if ok && result.secretHash == secretHash && time.Now().Before(result.expiration) {
+		return result.allowed, result.failedReview, nil
+	}
+
+	kubeClient, err := buildClient()
+	if err != nil {
+		return false, nil, err
+	}
+
+	allowed, failedReview, err := r.createSelfSubjectAccessReviews(ctx, kubeClient, reviews)
+	if err != nil {
+		return false, failedReview, err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	// the RBAC is changed during the reviews, the result may be stale
+	if r.generation != generation {
+		return allowed, failedReview, nil
+	}
+
+	now := time.Now()
+	for cachedKey, cachedResult := range r.results {
+		if now.After(cachedResult.expiration) {
+			delete(r.results, cachedKey)
+		}
+	}
+	r.results[key] = accessReviewResult{
+		allowed:      allowed,
+		failedReview: failedReview,
+		secretHash:   secretHash,
+		expiration:   now.Add(r.ttl),
+	}
+	return allowed, failedReview, nil
+}
+
+// invalidate removes the cached results of the clusters in the scope
+func (r *accessReviewer) invalidate(scope reviewScope) {
+	if scope.empty() {
+		return
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.generation++
+	for key := range r.results {
+		if scope.has(key.clusterName) {
+			delete(r.results, key)
+		}
+	}
+}
+
+// createSelfSubjectAccessReviews creates the reviews concurrently, the first failed review in the given order is
+// returned, so the result is stable.
+func (r *accessReviewer) createSelfSubjectAccessReviews(
+	ctx context.Context,
+	kubeClient kubernetes.Interface,
+	selfSubjectAccessReviews []authorizationv1.SelfSubjectAccessReview) (bool, *authorizationv1.SelfSubjectAccessReview, error) {
+	allowed := make([]bool, len(selfSubjectAccessReviews))
+	errs := make([]error, len(selfSubjectAccessReviews))
+
+	wg := sync.WaitGroup{}
+	for i := range selfSubjectAccessReviews {
+		select {
+		case r.workers <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return false, nil, ctx.Err()
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-r.workers
+				wg.Done()
+			}()
+
+			subjectAccessReview := selfSubjectAccessReviews[i]
+			ssar, err := kubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(
+				ctx, &subjectAccessReview, metav1.CreateOptions{})
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			allowed[i] = ssar.Status.Allowed
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range selfSubjectAccessReviews {
+		if errs[i] != nil {
+			return false, &selfSubjectAccessReviews[i], errs[i]
+		}
+		if !allowed[i] {
+			return false, &selfSubjectAccessReviews[i], nil
+		}
+	}
+	return true, nil, nil
+}
+
+func hashSecretData(secret *corev1.Secret) string {
+	keys := make([]string, 0, len(secret.Data))
+	for key := range secret.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		hash.Write([]byte(key))
+		hash.Write([]byte{0})
+		hash.Write(secret.Data[key])
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
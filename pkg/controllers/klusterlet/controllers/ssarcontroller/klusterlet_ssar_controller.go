@@ -14,10 +14,14 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	coreinformer "k8s.io/client-go/informers/core/v1"
+	rbacinformer "k8s.io/client-go/informers/rbac/v1"
 	"k8s.io/client-go/kubernetes"
 	corelister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 
 	operatorv1client "open-cluster-management.io/api/client/operator/clientset/versioned/typed/operator/v1"
@@ -36,6 +40,8 @@ type ssarController struct {
 	secretLister     corelister.SecretLister
 	klusterletClient operatorv1client.KlusterletInterface
 	klusterletLister operatorlister.KlusterletLister
+	reviewer         *accessReviewer
+	rbacScope        *rbacReviewScope
 	*klusterletLocker
 }
 
@@ -53,6 +59,7 @@ func NewKlusterletSSARController(
 	klusterletClient operatorv1client.KlusterletInterface,
 	klusterletInformer operatorinformer.KlusterletInformer,
 	secretInformer coreinformer.SecretInformer,
+	controlplaneRBACInformers rbacinformer.Interface,
 	recorder events.Recorder,
 ) factory.Controller {
 	controller := &ssarController{
@@ -60,20 +67,93 @@ func NewKlusterletSSARController(
 		klusterletClient: klusterletClient,
 		klusterletLister: klusterletInformer.Lister(),
 		secretLister:     secretInformer.Lister(),
+		reviewer:         newAccessReviewer(SSARWorkers, SSARCacheTTL),
+		rbacScope: &rbacReviewScope{
+			clusterRoleBindingLister: controlplaneRBACInformers.ClusterRoleBindings().Lister(),
+			roleBindingLister:        controlplaneRBACInformers.RoleBindings().Lister(),
+		},
 		klusterletLocker: &klusterletLocker{
 			klusterletInChecking: make(map[string]struct{}),
 		},
 	}
 
-	return factory.New().WithSync(controller.sync).
+	// the cached review results of the clusters are invalidated once their RBAC is changed on the controlplane
+	syncCtx := factory.NewSyncContext("KlusterletSSARController", recorder)
+	rbacInformers := []factory.Informer{
+		controlplaneRBACInformers.ClusterRoles().Informer(),
+		controlplaneRBACInformers.ClusterRoleBindings().Informer(),
+		controlplaneRBACInformers.Roles().Informer(),
+		controlplaneRBACInformers.RoleBindings().Informer(),
+	}
+	for _, informer := range rbacInformers {
+		informer.AddEventHandler(controller.rbacEventHandler(syncCtx.Queue()))
+	}
+
+	return factory.New().WithSyncContext(syncCtx).WithSync(controller.sync).
 		WithInformersQueueKeyFunc(helpers.KlusterletSecretQueueKeyFunc(controller.klusterletLister), secretInformer.Informer()).
 		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
 			accessor, _ := meta.Accessor(obj)
 			return accessor.GetName()
 		}, klusterletInformer.Informer()).
+		WithBareInformers(rbacInformers...).
 		ToController("KlusterletSSARController", recorder)
 }
 
+// rbacEventHandler invalidates the cached review results of the clusters that are affected by the RBAC change and
+// reviews their klusterlets again, the resyncs of the RBAC objects are ignored.
+func (c *ssarController) rbacEventHandler(queue workqueue.RateLimitingInterface) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			c.invalidate(queue, c.rbacScope.scope(obj))
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldAccessor, err := meta.Accessor(oldObj)
+			if err != nil {
+				return
+			}
+			newAccessor, err := meta.Accessor(newObj)
+			if err != nil {
+				return
+			}
+			if oldAccessor.GetResourceVersion() == newAccessor.GetResourceVersion() {
+				return
+			}
+
+			// the subjects of a binding may be changed
+			scope := c.rbacScope.scope(oldObj)
+			scope.merge(c.rbacScope.scope(newObj))
+			c.invalidate(queue, scope)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			c.invalidate(queue, c.rbacScope.scope(obj))
+		},
+	}
+}
+
+func (c *ssarController) invalidate(queue workqueue.RateLimitingInterface, scope reviewScope) {
+	if scope.empty() {
+		return
+	}
+
+	c.reviewer.invalidate(scope)
+
+	klusterlets, err := c.klusterletLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list klusterlets, %v", err)
+		return
+	}
+	for _, klusterlet := range klusterlets {
+		// the cluster name of the klusterlet may be only known from its hub kubeconfig secret
+		clusterName := helpers.ClusterName(klusterlet)
+		if len(clusterName) == 0 || scope.has(clusterName) {
+			queue.Add(klusterlet.Name)
+		}
+	}
+}
+
 func (l *klusterletLocker) inSSARChecking(klusterletName string) bool {
 	l.RLock()
 	defer l.RUnlock()
@@ -98,6 +178,7 @@ func (c *ssarController) sync(ctx context.Context, controllerContext factory.Syn
 	if klusterletName == "" {
 		return nil
 	}
+
 	klusterlet, err := c.klusterletLister.Get(klusterletName)
 	switch {
 	case errors.IsNotFound(err):
@@ -107,6 +188,10 @@ func (c *ssarController) sync(ctx context.Context, controllerContext factory.Syn
 	}
 	klusterlet = klusterlet.DeepCopy()
 
+	if helpers.IsKlusterletPaused(klusterlet) {
+		return nil
+	}
+
 	// if the ssar checking is already processing, requeue it after 30s.
 	if c.inSSARChecking(klusterletName) {
 		klog.V(4).Infof("Reconciling Klusterlet %q is already processing now", klusterletName)
@@ -122,7 +207,7 @@ func (c *ssarController) sync(ctx context.Context, controllerContext factory.Syn
 		agentNamespace := helpers.AgentNamespace(klusterlet)
 
 		hubConfigDegradedCondition := checkAgentDegradedCondition(
-			ctx, c.kubeClient,
+			ctx, c.kubeClient, c.reviewer,
 			hubConnectionDegraded,
 			klusterletAgent{
 				klusterlet: klusterlet,
@@ -151,7 +236,7 @@ func (c *ssarController) sync(ctx context.Context, controllerContext factory.Syn
 		}
 
 		bootstrapDegradedCondition := checkAgentDegradedCondition(
-			ctx, c.kubeClient,
+			ctx, c.kubeClient, c.reviewer,
 			hubConnectionDegraded,
 			klusterletAgent{
 				klusterlet: klusterlet,
@@ -190,21 +275,23 @@ type klusterletAgent struct {
 }
 
 func checkAgentDegradedCondition(
-	ctx context.Context, kubeClient kubernetes.Interface,
+	ctx context.Context, kubeClient kubernetes.Interface, reviewer *accessReviewer,
 	degradedType string,
 	agent klusterletAgent,
 	generation int64,
 	degradedCheckFn degradedCheckFunc) metav1.Condition {
-	currCond := degradedCheckFn(ctx, kubeClient, agent)
+	currCond := degradedCheckFn(ctx, kubeClient, reviewer, agent)
 	currCond.Type = degradedType
 	currCond.ObservedGeneration = generation
 	return currCond
 }
 
-type degradedCheckFunc func(ctx context.Context, kubeClient kubernetes.Interface, agent klusterletAgent) metav1.Condition
+type degradedCheckFunc func(ctx context.Context, kubeClient kubernetes.Interface, reviewer *accessReviewer,
+	agent klusterletAgent) metav1.Condition
 
 // Check bootstrap secret, if the secret is invalid, return registration degraded condition
-func checkBootstrapSecret(ctx context.Context, kubeClient kubernetes.Interface, agent klusterletAgent) metav1.Condition {
+func checkBootstrapSecret(ctx context.Context, kubeClient kubernetes.Interface, reviewer *accessReviewer,
+	agent klusterletAgent) metav1.Condition {
 	bootstrapHubKubeConfigSecret := helpers.BootstrapHubKubeConfigSecret(agent.klusterlet)
 	// Check if bootstrap secret exists
 	bootstrapSecret, err := kubeClient.CoreV1().Secrets(agent.namespace).Get(ctx, bootstrapHubKubeConfigSecret, metav1.GetOptions{})
@@ -217,7 +304,7 @@ func checkBootstrapSecret(ctx context.Context, kubeClient kubernetes.Interface,
 	}
 
 	// Check if bootstrap secret works by building kube client
-	bootstrapClient, host, err := buildKubeClientWithSecret(bootstrapSecret)
+	host, err := secretHost(bootstrapSecret)
 	if err != nil {
 		return metav1.Condition{
 			Status: metav1.ConditionTrue,
@@ -228,7 +315,11 @@ func checkBootstrapSecret(ctx context.Context, kubeClient kubernetes.Interface,
 	}
 
 	// Check the bootstrap client permissions by creating SelfSubjectAccessReviews
-	allowed, failedReview, err := createSelfSubjectAccessReviews(ctx, bootstrapClient, getBootstrapSSARs())
+	allowed, failedReview, err := reviewer.review(ctx, helpers.ClusterName(agent.klusterlet), bootstrapSecret,
+		"bootstrap", getBootstrapSSARs(),
+		func() (kubernetes.Interface, error) {
+			return buildKubeClientWithSecret(bootstrapSecret)
+		})
 	if err != nil {
 		return metav1.Condition{
 			Status: metav1.ConditionTrue,
@@ -270,7 +361,8 @@ func getBootstrapSSARs() []authorizationv1.SelfSubjectAccessReview {
 }
 
 // Check hub-kubeconfig-secret, if the secret is invalid, return degraded condition
-func checkHubConfigSecret(ctx context.Context, kubeClient kubernetes.Interface, agent klusterletAgent) metav1.Condition {
+func checkHubConfigSecret(ctx context.Context, kubeClient kubernetes.Interface, reviewer *accessReviewer,
+	agent klusterletAgent) metav1.Condition {
 	hubkubeConfigSecret := helpers.HubKubeConfigSecret(agent.klusterlet)
 	hubConfigSecret, err := kubeClient.CoreV1().Secrets(agent.namespace).Get(ctx, hubkubeConfigSecret, metav1.GetOptions{})
 	if err != nil {
@@ -291,7 +383,7 @@ func checkHubConfigSecret(ctx context.Context, kubeClient kubernetes.Interface,
 		}
 	}
 
-	hubClient, host, err := buildKubeClientWithSecret(hubConfigSecret)
+	host, err := secretHost(hubConfigSecret)
 	if err != nil {
 		return metav1.Condition{
 			Status: metav1.ConditionTrue,
@@ -317,7 +409,11 @@ func checkHubConfigSecret(ctx context.Context, kubeClient kubernetes.Interface,
 	}
 
 	// Check the hub kubeconfig permissions by creating SelfSubjectAccessReviews
-	allowed, failedReview, err := createSelfSubjectAccessReviews(ctx, hubClient, getHubConfigSSARs(clusterName))
+	allowed, failedReview, err := reviewer.review(ctx, clusterName, hubConfigSecret, "hub",
+		getHubConfigSSARs(clusterName),
+		func() (kubernetes.Interface, error) {
+			return buildKubeClientWithSecret(hubConfigSecret)
+		})
 	if err != nil {
 		return metav1.Condition{
 			Status: metav1.ConditionTrue,
@@ -414,15 +510,24 @@ func getHubConfigSSARs(clusterName string) []authorizationv1.SelfSubjectAccessRe
 	return reviews
 }
 
-func buildKubeClientWithSecret(secret *corev1.Secret) (kubernetes.Interface, string, error) {
+func secretHost(secret *corev1.Secret) (string, error) {
+	restConfig, err := helpers.LoadClientConfigFromSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return restConfig.Host, nil
+}
+
+func buildKubeClientWithSecret(secret *corev1.Secret) (kubernetes.Interface, error) {
 	restConfig, err := helpers.LoadClientConfigFromSecret(secret)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
-	// reduce qps and burst of client, because too many managed clusters registration on hub and send ssar requests at once could cause resource pressure
-	restConfig.QPS = 2
-	restConfig.Burst = 5
+	// reduce qps and burst of client, because too many managed clusters registration on hub and send ssar requests at once could cause resource pressure,
+	// the concurrent ssar requests of all klusterlets are also bounded by the worker pool of the access reviewer
+	restConfig.QPS = 5
+	restConfig.Burst = 10
 
 	// TODO(@Promacanthus): When server field is a domain name in kubeconfig, such as https://xxx.yyy.zzz, and there is no such hostname in the DNS,
 	// we need to set the hostAliases for the hub Api server in klusterlet cr and .pod.spec.hostAliases.
@@ -435,12 +540,7 @@ func buildKubeClientWithSecret(secret *corev1.Secret) (kubernetes.Interface, str
 	// 	return net.Dial(network, "this is the hub api server ip address")
 	// }
 
-	client, err := kubernetes.NewForConfig(restConfig)
-	if err != nil {
-		return nil, "", err
-	}
-
-	return client, restConfig.Host, nil
+	return kubernetes.NewForConfig(restConfig)
 }
 
 func generateSelfSubjectAccessReviews(resource authorizationv1.ResourceAttributes, verbs ...string) []authorizationv1.SelfSubjectAccessReview {
@@ -461,22 +561,3 @@ func generateSelfSubjectAccessReviews(resource authorizationv1.ResourceAttribute
 	}
 	return reviews
 }
-
-func createSelfSubjectAccessReviews(
-	ctx context.Context,
-	kubeClient kubernetes.Interface,
-	selfSubjectAccessReviews []authorizationv1.SelfSubjectAccessReview) (bool, *authorizationv1.SelfSubjectAccessReview, error) {
-
-	for i := range selfSubjectAccessReviews {
-		subjectAccessReview := selfSubjectAccessReviews[i]
-
-		ssar, err := kubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &subjectAccessReview, metav1.CreateOptions{})
-		if err != nil {
-			return false, &subjectAccessReview, err
-		}
-		if !ssar.Status.Allowed {
-			return false, &subjectAccessReview, nil
-		}
-	}
-	return true, nil, nil
-}
@@ -0,0 +1,121 @@
+package ssarcontroller
+
+import (
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	rbaclister "k8s.io/client-go/listers/rbac/v1"
+)
+
+const (
+	// clusterSubjectPrefix is the prefix of the user and group of the hub kubeconfig of a managed cluster, e.g.
+	// system:open-cluster-management:<cluster name>:<agent id>
+	clusterSubjectPrefix = "system:open-cluster-management:"
+	// managedClustersGroup is the group of the hub kubeconfig of all managed clusters
+	managedClustersGroup = "system:open-cluster-management:managed-clusters"
+
+	serviceAccountsGroup       = "system:serviceaccounts"
+	serviceAccountsGroupPrefix = "system:serviceaccounts:"
+)
+
+// reviewScope is the clusters whose review results may be changed by a RBAC change.
+type reviewScope struct {
+	all      bool
+	clusters sets.Set[string]
+}
+
+func clusterReviewScope(clusterName string) reviewScope {
+	return reviewScope{clusters: sets.New[string](clusterName)}
+}
+
+func (s reviewScope) empty() bool {
+	return !s.all && s.clusters.Len() == 0
+}
+
+func (s reviewScope) has(clusterName string) bool {
+	return s.all || s.clusters.Has(clusterName)
+}
+
+func (s *reviewScope) merge(other reviewScope) {
+	s.all = s.all || other.all
+	if other.clusters.Len() == 0 {
+		return
+	}
+	if s.clusters == nil {
+		s.clusters = sets.New[string]()
+	}
+	s.clusters.Insert(other.clusters.UnsortedList()...)
+}
+
+// rbacReviewScope computes the scope of the change of a RBAC object on the controlplane. The namespaced RBAC objects
+// only change the permissions in the managed cluster namespace, and the cluster RBAC objects only change the
+// permissions of the managed clusters in their subjects.
+type rbacReviewScope struct {
+	clusterRoleBindingLister rbaclister.ClusterRoleBindingLister
+	roleBindingLister        rbaclister.RoleBindingLister
+}
+
+func (r *rbacReviewScope) scope(obj interface{}) reviewScope {
+	switch rbacObj := obj.(type) {
+	case *rbacv1.Role:
+		return clusterReviewScope(rbacObj.Namespace)
+	case *rbacv1.RoleBinding:
+		return clusterReviewScope(rbacObj.Namespace)
+	case *rbacv1.ClusterRoleBinding:
+		return subjectsReviewScope(rbacObj.Subjects)
+	case *rbacv1.ClusterRole:
+		return r.clusterRoleScope(rbacObj.Name)
+	}
+	return reviewScope{}
+}
+
+// clusterRoleScope returns the scope of the bindings of the cluster role
+func (r *rbacReviewScope) clusterRoleScope(name string) reviewScope {
+	scope := reviewScope{}
+
+	clusterRoleBindings, err := r.clusterRoleBindingLister.List(labels.Everything())
+	if err != nil {
+		return reviewScope{all: true}
+	}
+	for _, binding := range clusterRoleBindings {
+		if binding.RoleRef.Kind == "ClusterRole" && binding.RoleRef.Name == name {
+			scope.merge(subjectsReviewScope(binding.Subjects))
+		}
+	}
+
+	roleBindings, err := r.roleBindingLister.List(labels.Everything())
+	if err != nil {
+		return reviewScope{all: true}
+	}
+	for _, binding := range roleBindings {
+		if binding.RoleRef.Kind == "ClusterRole" && binding.RoleRef.Name == name {
+			scope.merge(clusterReviewScope(binding.Namespace))
+		}
+	}
+
+	return scope
+}
+
+// subjectsReviewScope returns the clusters of the subjects, the bootstrap service account of a cluster is in the
+// cluster namespace. The subjects that are not owned by a cluster, e.g. system:authenticated, change all clusters.
+func subjectsReviewScope(subjects []rbacv1.Subject) reviewScope {
+	scope := reviewScope{}
+	for _, subject := range subjects {
+		switch {
+		case subject.Kind == rbacv1.ServiceAccountKind:
+			scope.merge(clusterReviewScope(subject.Namespace))
+		case subject.Name == managedClustersGroup || subject.Name == serviceAccountsGroup:
+			return reviewScope{all: true}
+		case strings.HasPrefix(subject.Name, serviceAccountsGroupPrefix):
+			scope.merge(clusterReviewScope(strings.TrimPrefix(subject.Name, serviceAccountsGroupPrefix)))
+		case strings.HasPrefix(subject.Name, clusterSubjectPrefix):
+			clusterName := strings.Split(strings.TrimPrefix(subject.Name, clusterSubjectPrefix), ":")[0]
+			scope.merge(clusterReviewScope(clusterName))
+		default:
+			return reviewScope{all: true}
+		}
+	}
+	return scope
+}
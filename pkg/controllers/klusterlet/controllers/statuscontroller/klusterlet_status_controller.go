@@ -32,7 +32,7 @@ type klusterletStatusController struct {
 
 const (
 	klusterletAgentDesiredDegraded = "AgentDesiredDegraded"
-	klusterletAvailable            = "Available"
+	klusterletAvailable            = helpers.KlusterletAvailable
 )
 
 // NewKlusterletStatusController returns a klusterletStatusController
@@ -69,6 +69,11 @@ func (k *klusterletStatusController) sync(ctx context.Context, controllerContext
 	}
 	klusterlet = klusterlet.DeepCopy()
 
+	// the agent may be scaled down when the klusterlet is paused, keep the last status
+	if helpers.IsKlusterletPaused(klusterlet) {
+		return nil
+	}
+
 	agentNamespace := helpers.AgentNamespace(klusterlet)
 	agentDeploymentName := fmt.Sprintf("%s-multicluster-controlplane-agent", klusterlet.Name)
 
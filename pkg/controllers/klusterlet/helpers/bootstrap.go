@@ -0,0 +1,223 @@
+// Copyright Contributors to the Open Cluster Management project
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/utils/pointer"
+	propagatorctrl "open-cluster-management.io/governance-policy-propagator/controllers/propagator"
+)
+
+const (
+	// BootstrapKubeConfigSecretKey and BootstrapTokenSecretKey are the keys of the bootstrap kubeconfig and its token
+	// in the bootstrap kubeconfig secret, the agent reloads the token after the secret is refreshed.
+	BootstrapKubeConfigSecretKey = "kubeconfig"
+	BootstrapTokenSecretKey      = "token"
+
+	bootstrapClusterRole = "system:open-cluster-management:bootstrap"
+	bootstrapAgentRole   = "open-cluster-management:bootstrap:agent"
+)
+
+// bootstrapAgentRules are the permissions of the agent addons in the managed cluster namespace
+var bootstrapAgentRules = []rbacv1.PolicyRule{
+	{
+		APIGroups: []string{"policy.open-cluster-management.io"},
+		Resources: []string{"policies", "policies/status", "policies/finalizers"},
+		Verbs:     []string{"get", "list", "watch", "update", "patch"},
+	},
+	{
+		APIGroups: []string{"internal.open-cluster-management.io"},
+		Resources: []string{"managedclusterinfos", "managedclusterinfos/status"},
+		Verbs:     []string{"get", "list", "watch", "update", "patch"},
+	},
+	{
+		APIGroups: []string{"view.open-cluster-management.io"},
+		Resources: []string{"managedclusterviews", "managedclusterviews/status"},
+		Verbs:     []string{"get", "list", "watch", "update", "patch"},
+	},
+	{
+		APIGroups: []string{"action.open-cluster-management.io"},
+		Resources: []string{"managedclusteractions", "managedclusteractions/status"},
+		Verbs:     []string{"get", "list", "watch", "update", "patch"},
+	},
+	{
+		// only the policy encryption key can be read, the list and watch are only allowed with the field selector of
+		// its name, which is how the agent caches it.
+		APIGroups:     []string{""},
+		Resources:     []string{"secrets"},
+		ResourceNames: []string{propagatorctrl.EncryptionKeySecret},
+		Verbs:         []string{"get", "list", "watch"},
+	},
+	{
+		APIGroups: []string{"", "events.k8s.io"},
+		Resources: []string{"events"},
+		Verbs:     []string{"get", "list", "watch", "create", "update", "patch"},
+	},
+}
+
+// BootstrapServiceAccountName returns the name of the bootstrap service account in the managed cluster namespace
+func BootstrapServiceAccountName(clusterName string) string {
+	return fmt.Sprintf("%s-bootstrap-sa", clusterName)
+}
+
+func bootstrapClusterRoleBindingName(clusterName string) string {
+	return fmt.Sprintf("open-cluster-management:bootstrap:%s", clusterName)
+}
+
+// EnsureBootstrapServiceAccount creates the bootstrap service account in the managed cluster namespace of the
+// controlplane, the service account is bound to the bootstrap cluster role to register the managed cluster, and it
+// can access the resources of the agent addons in the managed cluster namespace.
+func EnsureBootstrapServiceAccount(ctx context.Context,
+	kubeClient kubernetes.Interface, clusterName string, recorder events.Recorder) error {
+	_, err := kubeClient.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: clusterName},
+	}, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	saName := BootstrapServiceAccountName(clusterName)
+	subjects := []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: saName, Namespace: clusterName}}
+
+	if _, _, err := resourceapply.ApplyServiceAccount(ctx, kubeClient.CoreV1(), recorder,
+		&corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{Name: saName, Namespace: clusterName},
+		}); err != nil {
+		return err
+	}
+
+	if _, _, err := resourceapply.ApplyClusterRoleBinding(ctx, kubeClient.RbacV1(), recorder,
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: bootstrapClusterRoleBindingName(clusterName)},
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: rbacv1.GroupName,
+				Kind:     "ClusterRole",
+				Name:     bootstrapClusterRole,
+			},
+			Subjects: subjects,
+		}); err != nil {
+		return err
+	}
+
+	if _, _, err := resourceapply.ApplyRole(ctx, kubeClient.RbacV1(), recorder,
+		&rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Name: bootstrapAgentRole, Namespace: clusterName},
+			Rules:      bootstrapAgentRules,
+		}); err != nil {
+		return err
+	}
+
+	_, _, err = resourceapply.ApplyRoleBinding(ctx, kubeClient.RbacV1(), recorder,
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: bootstrapAgentRole, Namespace: clusterName},
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: rbacv1.GroupName,
+				Kind:     "Role",
+				Name:     bootstrapAgentRole,
+			},
+			Subjects: subjects,
+		})
+	return err
+}
+
+// DeleteBootstrapServiceAccount deletes the bootstrap service account and its bindings from the managed cluster
+// namespace of the controlplane.
+func DeleteBootstrapServiceAccount(ctx context.Context, kubeClient kubernetes.Interface, clusterName string) error {
+	err := kubeClient.RbacV1().ClusterRoleBindings().Delete(
+		ctx, bootstrapClusterRoleBindingName(clusterName), metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	err = kubeClient.RbacV1().RoleBindings(clusterName).Delete(ctx, bootstrapAgentRole, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	err = kubeClient.RbacV1().Roles(clusterName).Delete(ctx, bootstrapAgentRole, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	err = kubeClient.CoreV1().ServiceAccounts(clusterName).Delete(
+		ctx, BootstrapServiceAccountName(clusterName), metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// ControlplaneServerAndCA returns the server and ca of the controlplane from the cluster-info configmap in the
+// kube-public namespace.
+func ControlplaneServerAndCA(ctx context.Context, kubeClient kubernetes.Interface) (string, []byte, error) {
+	clusterInfo, err := kubeClient.CoreV1().ConfigMaps(metav1.NamespacePublic).Get(
+		ctx, "cluster-info", metav1.GetOptions{})
+	if err != nil {
+		return "", nil, err
+	}
+
+	clusterInfoConfig, err := clientcmd.Load([]byte(clusterInfo.Data["kubeconfig"]))
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid kubeconfig in the cluster-info configmap, %v", err)
+	}
+
+	for _, cluster := range clusterInfoConfig.Clusters {
+		if len(cluster.Server) != 0 {
+			return cluster.Server, cluster.CertificateAuthorityData, nil
+		}
+	}
+	return "", nil, fmt.Errorf("there is no server in the cluster-info configmap")
+}
+
+// BuildBootstrapKubeConfig builds the data of the bootstrap kubeconfig secret with a new token of the bootstrap
+// service account, the token is also saved in the secret separately, so the agent can reload it after the secret is
+// refreshed. The expiration time of the token is returned.
+func BuildBootstrapKubeConfig(ctx context.Context, kubeClient kubernetes.Interface,
+	clusterName, server string, caData []byte, expiration time.Duration) (map[string][]byte, time.Time, error) {
+	tokenRequest, err := kubeClient.CoreV1().ServiceAccounts(clusterName).CreateToken(
+		ctx,
+		BootstrapServiceAccountName(clusterName),
+		&authenticationv1.TokenRequest{
+			Spec: authenticationv1.TokenRequestSpec{
+				ExpirationSeconds: pointer.Int64(int64(expiration.Seconds())),
+			},
+		},
+		metav1.CreateOptions{},
+	)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to request bootstrap token for managed cluster %s, %v",
+			clusterName, err)
+	}
+
+	kubeconfig, err := clientcmd.Write(clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{"controlplane": {
+			Server:                   server,
+			CertificateAuthorityData: caData,
+		}},
+		AuthInfos:      map[string]*clientcmdapi.AuthInfo{"bootstrap": {Token: tokenRequest.Status.Token}},
+		Contexts:       map[string]*clientcmdapi.Context{"bootstrap": {Cluster: "controlplane", AuthInfo: "bootstrap"}},
+		CurrentContext: "bootstrap",
+	})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return map[string][]byte{
+		BootstrapKubeConfigSecretKey: kubeconfig,
+		BootstrapTokenSecretKey:      []byte(tokenRequest.Status.Token),
+	}, tokenRequest.Status.ExpirationTimestamp.Time, nil
+}
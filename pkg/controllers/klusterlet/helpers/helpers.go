@@ -7,6 +7,7 @@ import (
 	"os"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/openshift/api"
 	"github.com/openshift/library-go/pkg/operator/events"
@@ -50,6 +51,52 @@ const (
 
 const KlusterletOwnerAnnotation = "operator.open-cluster-management.io/klusterlet-owner"
 
+// KlusterletDeletionModeAnnotation selects how the resources of a klusterlet are handled when the klusterlet is
+// deleted, with the KlusterletDeletionModeDetach, the agent and the controlplane records of the klusterlet are
+// removed, but the resources applied by the agent and the CRDs are kept on the managed cluster.
+const (
+	KlusterletDeletionModeAnnotation = "operator.open-cluster-management.io/deletion-mode"
+	KlusterletDeletionModeDetach     = "Detach"
+)
+
+// The annotations and the condition of the klusterlet migration, a hosted klusterlet is migrated to a target
+// controlplane when it has the MigrationTargetSecretAnnotation, the annotation value is the name of a secret in the
+// managed cluster namespace on the controlplane, the secret has the kubeconfig of the target controlplane.
+const (
+	MigrationTargetSecretAnnotation = "operator.open-cluster-management.io/migration-target-secret"
+	// MigratingFromAnnotation is set on the klusterlet of the target controlplane until the migration is completed
+	MigratingFromAnnotation = "operator.open-cluster-management.io/migrating-from"
+	// BootstrapKubeConfigSecretAnnotation overrides the bootstrap kubeconfig secret of a hosted klusterlet agent
+	BootstrapKubeConfigSecretAnnotation = "operator.open-cluster-management.io/bootstrap-kubeconfig-secret"
+
+	KlusterletMigrated                 = "Migrated"
+	KlusterletMigrationExporting       = "MigrationExporting"
+	KlusterletMigrationRebootstrapping = "MigrationRebootstrapping"
+	KlusterletMigrationDetaching       = "MigrationDetaching"
+	KlusterletMigrationImporting       = "MigrationImporting"
+	KlusterletMigrationCompleted       = "MigrationCompleted"
+	KlusterletMigrationFailed          = "MigrationFailed"
+)
+
+// KlusterletAvailable is the condition type that reports whether the agent of a klusterlet is available
+const KlusterletAvailable = "Available"
+
+// The annotations to pause a klusterlet, the klusterlet controllers stop reconciling a paused klusterlet.
+const (
+	// KlusterletPausedAnnotation pauses the klusterlet when its value is true
+	KlusterletPausedAnnotation = "operator.open-cluster-management.io/paused"
+	// KlusterletMaintenanceWindowAnnotation pauses the klusterlet in a time window, its value is a time interval of
+	// two RFC3339 times, e.g. 2023-06-01T01:00:00Z/2023-06-01T03:00:00Z, the agent of a hosted klusterlet is scaled
+	// down in the maintenance window.
+	KlusterletMaintenanceWindowAnnotation = "operator.open-cluster-management.io/maintenance-window"
+
+	KlusterletPaused = "Paused"
+)
+
+// AddOnFeatureGatesAnnotation toggles the agent addons of a klusterlet, its value is a comma separated list of
+// <feature>=<true|false>, e.g. ConfigurationPolicy=false,ManagedClusterInfo=true
+const AddOnFeatureGatesAnnotation = "operator.open-cluster-management.io/addon-feature-gates"
+
 var (
 	genericScheme = runtime.NewScheme()
 	genericCodecs = serializer.NewCodecFactory(genericScheme)
@@ -658,6 +705,38 @@ func ConvertToFeatureGateFlags(component string, features []operatorapiv1.Featur
 	return flags, ""
 }
 
+// AddOnFeatureGates returns the addon feature gates from the AddOnFeatureGatesAnnotation of a klusterlet, a feature
+// without a value is enabled, and a malformed item is returned as an unknown feature, so that it can be reported as an
+// invalid feature gate.
+func AddOnFeatureGates(klusterlet *operatorapiv1.Klusterlet) []operatorapiv1.FeatureGate {
+	var features []operatorapiv1.FeatureGate
+	value := strings.TrimSpace(klusterlet.Annotations[AddOnFeatureGatesAnnotation])
+	if len(value) == 0 {
+		return features
+	}
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		name, enabled, found := strings.Cut(item, "=")
+		switch {
+		case len(item) == 0:
+			continue
+		case !found:
+			features = append(features, operatorapiv1.FeatureGate{
+				Feature: item, Mode: operatorapiv1.FeatureGateModeTypeEnable})
+		case found && strings.TrimSpace(enabled) == "true":
+			features = append(features, operatorapiv1.FeatureGate{
+				Feature: strings.TrimSpace(name), Mode: operatorapiv1.FeatureGateModeTypeEnable})
+		case found && strings.TrimSpace(enabled) == "false":
+			features = append(features, operatorapiv1.FeatureGate{
+				Feature: strings.TrimSpace(name), Mode: operatorapiv1.FeatureGateModeTypeDisable})
+		default:
+			features = append(features, operatorapiv1.FeatureGate{Feature: item})
+		}
+	}
+	return features
+}
+
 // FeatureGateEnabled checks if a feature is enabled or disabled in operator API, or fallback to use the
 // the default setting
 func FeatureGateEnabled(features []operatorapiv1.FeatureGate, defaultFeatures map[featuregate.Feature]featuregate.FeatureSpec, featureName featuregate.Feature) bool {
@@ -675,6 +754,67 @@ func FeatureGateEnabled(features []operatorapiv1.FeatureGate, defaultFeatures ma
 	return defaultFeature.Default
 }
 
+// IsKlusterletDetached returns true if the klusterlet is deleted with the detach mode
+func IsKlusterletDetached(klusterlet *operatorapiv1.Klusterlet) bool {
+	return klusterlet.Annotations[KlusterletDeletionModeAnnotation] == KlusterletDeletionModeDetach
+}
+
+// IsKlusterletMigrating returns true if the klusterlet is migrated from another controlplane and the migration is not
+// completed yet
+func IsKlusterletMigrating(klusterlet *operatorapiv1.Klusterlet) bool {
+	_, ok := klusterlet.Annotations[MigratingFromAnnotation]
+	return ok
+}
+
+// IsKlusterletMigratedOut returns true if the klusterlet has been migrated to another controlplane and it is being
+// detached from the current controlplane
+func IsKlusterletMigratedOut(klusterlet *operatorapiv1.Klusterlet) bool {
+	cond := meta.FindStatusCondition(klusterlet.Status.Conditions, KlusterletMigrated)
+	return cond != nil && cond.Reason == KlusterletMigrationDetaching
+}
+
+// IsKlusterletPaused returns true if the klusterlet is paused with the pause annotation or it is in its maintenance
+// window
+func IsKlusterletPaused(klusterlet *operatorapiv1.Klusterlet) bool {
+	if strings.EqualFold(klusterlet.Annotations[KlusterletPausedAnnotation], "true") {
+		return true
+	}
+
+	start, end, ok, err := KlusterletMaintenanceWindow(klusterlet)
+	if !ok || err != nil {
+		return false
+	}
+
+	now := time.Now()
+	return !now.Before(start) && now.Before(end)
+}
+
+// KlusterletMaintenanceWindow returns the maintenance window of the klusterlet, ok is false if the klusterlet does not
+// have a maintenance window.
+func KlusterletMaintenanceWindow(klusterlet *operatorapiv1.Klusterlet) (start, end time.Time, ok bool, err error) {
+	window, ok := klusterlet.Annotations[KlusterletMaintenanceWindowAnnotation]
+	if !ok {
+		return start, end, false, nil
+	}
+
+	startValue, endValue, found := strings.Cut(window, "/")
+	if !found {
+		return start, end, true, fmt.Errorf("the maintenance window %q should be <start>/<end>", window)
+	}
+
+	if start, err = time.Parse(time.RFC3339, strings.TrimSpace(startValue)); err != nil {
+		return start, end, true, fmt.Errorf("invalid start of the maintenance window %q, %v", window, err)
+	}
+	if end, err = time.Parse(time.RFC3339, strings.TrimSpace(endValue)); err != nil {
+		return start, end, true, fmt.Errorf("invalid end of the maintenance window %q, %v", window, err)
+	}
+	if !end.After(start) {
+		return start, end, true, fmt.Errorf("the end of the maintenance window %q should be after its start", window)
+	}
+
+	return start, end, true, nil
+}
+
 func GetComponentNamespace() string {
 	nsBytes, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
 	if err != nil {
@@ -683,8 +823,30 @@ func GetComponentNamespace() string {
 	return string(nsBytes)
 }
 
+// GetControlplaneImage returns the image of the current running controlplane, the agents use the same image with
+// the controlplane.
+func GetControlplaneImage(ctx context.Context, kubeClient kubernetes.Interface) (string, error) {
+	name := "multicluster-controlplane"
+	deploy, err := kubeClient.AppsV1().Deployments(GetComponentNamespace()).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	for _, c := range deploy.Spec.Template.Spec.Containers {
+		if c.Name == "controlplane" {
+			return c.Image, nil
+		}
+	}
+
+	return "", fmt.Errorf("faild to find current controlplane image from `controlplane` container in deployment %s", name)
+}
+
 func BootstrapHubKubeConfigSecret(klusterlet *operatorapiv1.Klusterlet) string {
 	if klusterlet.Spec.DeployOption.Mode == operatorapiv1.InstallModeHosted {
+		if secretName := klusterlet.Annotations[BootstrapKubeConfigSecretAnnotation]; len(secretName) != 0 {
+			return secretName
+		}
+
 		return "multicluster-controlplane-svc-kubeconfig"
 	}
 
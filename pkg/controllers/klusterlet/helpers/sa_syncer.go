@@ -4,6 +4,7 @@ package helpers
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"time"
@@ -26,56 +27,30 @@ import (
 
 type TokenGetterFunc func() ([]byte, []byte, error)
 
-// SATokenGetter get the saToken of target sa. If there is not secrets in the sa, use the tokenrequest to get a token.
-func SATokenGetter(ctx context.Context, saName, saNamespace string, saClient kubernetes.Interface) TokenGetterFunc {
+// SATokenGetter gets a bound token of the target sa with the tokenrequest, the token expires after the expiration.
+func SATokenGetter(ctx context.Context, saName, saNamespace string, saClient kubernetes.Interface,
+	expiration time.Duration) TokenGetterFunc {
 	return func() ([]byte, []byte, error) {
-		// get the service account
-		sa, err := saClient.CoreV1().ServiceAccounts(saNamespace).Get(ctx, saName, metav1.GetOptions{})
-		if err != nil {
-			return nil, nil, err
-		}
-
-		for _, secret := range sa.Secrets {
-			// get the token secret
-			tokenSecretName := secret.Name
-
-			// get the token secret
-			tokenSecret, err := saClient.CoreV1().Secrets(saNamespace).Get(ctx, tokenSecretName, metav1.GetOptions{})
-			if err != nil {
-				return nil, nil, err
-			}
-
-			if tokenSecret.Type != corev1.SecretTypeServiceAccountToken {
-				continue
-			}
-
-			saToken, ok := tokenSecret.Data["token"]
-			if !ok {
-				continue
-			}
-
-			return saToken, nil, nil
-		}
-
-		// 8640 hour
 		tr, err := saClient.CoreV1().ServiceAccounts(saNamespace).
 			CreateToken(ctx, saName, &authv1.TokenRequest{
 				Spec: authv1.TokenRequestSpec{
-					ExpirationSeconds: pointer.Int64(8640 * 3600),
+					ExpirationSeconds: pointer.Int64(int64(expiration.Seconds())),
 				},
 			}, metav1.CreateOptions{})
 		if err != nil {
 			return nil, nil, err
 		}
-		expiration, err := tr.Status.ExpirationTimestamp.MarshalText()
+		expirationTimestamp, err := tr.Status.ExpirationTimestamp.MarshalText()
 		if err != nil {
-			return nil, nil, nil
+			return nil, nil, err
 		}
-		return []byte(tr.Status.Token), expiration, nil
+		return []byte(tr.Status.Token), expirationTimestamp, nil
 	}
 }
 
-func SyncKubeConfigSecret(ctx context.Context, secretName, secretNamespace, kubeconfigPath string, templateKubeconfig *rest.Config, secretClient coreclientv1.SecretsGetter, tokenGetter TokenGetterFunc, recorder events.Recorder) error {
+// SyncKubeConfigSecret applies the kubeconfig secret with a token from the tokenGetter, the token is refreshed when
+// a fifth of the token ttl remains, or the token lifetime is longer than the token ttl, e.g. the ttl is shortened.
+func SyncKubeConfigSecret(ctx context.Context, secretName, secretNamespace, kubeconfigPath string, templateKubeconfig *rest.Config, secretClient coreclientv1.SecretsGetter, tokenGetter TokenGetterFunc, tokenTTL time.Duration, recorder events.Recorder) error {
 	secret, err := secretClient.Secrets(secretNamespace).Get(ctx, secretName, metav1.GetOptions{})
 	switch {
 	case errors.IsNotFound(err):
@@ -84,33 +59,45 @@ func SyncKubeConfigSecret(ctx context.Context, secretName, secretNamespace, kube
 		return err
 	}
 
-	if tokenValid(secret) && clusterInfoNotChanged(secret, templateKubeconfig) {
+	if tokenValid(secret, tokenTTL) && clusterInfoNotChanged(secret, templateKubeconfig) {
 		return nil
 	}
 
 	return applyKubeconfigSecret(ctx, templateKubeconfig, secretName, secretNamespace, kubeconfigPath, secretClient, tokenGetter, recorder)
 }
 
-func tokenValid(secret *corev1.Secret) bool {
-	_, tokenFound := secret.Data["token"]
+// TokenExpiration returns the expiration time of the token in the kubeconfig secret, an error is returned if the
+// secret does not have a token or its expiration.
+func TokenExpiration(secret *corev1.Secret) (time.Time, error) {
+	if _, tokenFound := secret.Data["token"]; !tokenFound {
+		return time.Time{}, fmt.Errorf("the token is not found in the secret %s/%s", secret.Namespace, secret.Name)
+	}
+
 	expiration, expirationFound := secret.Data["expiration"]
+	if !expirationFound {
+		return time.Time{}, fmt.Errorf("the token expiration is not found in the secret %s/%s",
+			secret.Namespace, secret.Name)
+	}
+
+	return time.Parse(time.RFC3339, string(expiration))
+}
 
-	if !tokenFound {
+func tokenValid(secret *corev1.Secret, tokenTTL time.Duration) bool {
+	// the token without an expiration is a legacy token, it is replaced by a bound token
+	expirationTime, err := TokenExpiration(secret)
+	if err != nil {
 		return false
 	}
 
-	if expirationFound {
-		expirationTime, err := time.Parse(time.RFC3339, string(expiration))
-		if err != nil {
-			return false
-		}
+	refreshThreshold := tokenTTL / 5
+	lifetime := time.Until(expirationTime)
+	if lifetime < refreshThreshold {
+		return false
+	}
 
-		now := metav1.Now()
-		refreshThreshold := 8640 * time.Hour / 5
-		lifetime := expirationTime.Sub(now.Time)
-		if lifetime < refreshThreshold {
-			return false
-		}
+	// tolerate the clock skew between the controlplane and the managed cluster
+	if lifetime > tokenTTL+refreshThreshold {
+		return false
 	}
 
 	return true
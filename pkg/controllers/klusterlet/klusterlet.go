@@ -2,23 +2,31 @@ package klusterlet
 
 import (
 	"context"
+	"time"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
 
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	rbacinformers "k8s.io/client-go/informers/rbac/v1"
 	"k8s.io/client-go/kubernetes"
 
 	clusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterv1informers "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
 	operatorv1client "open-cluster-management.io/api/client/operator/clientset/versioned/typed/operator/v1"
 	operatorv1informers "open-cluster-management.io/api/client/operator/informers/externalversions/operator/v1"
 	workclient "open-cluster-management.io/api/client/work/clientset/versioned"
 	workv1client "open-cluster-management.io/api/client/work/clientset/versioned/typed/work/v1"
 	"open-cluster-management.io/multicluster-controlplane/pkg/util"
 
+	"github.com/stolostron/multicluster-controlplane/pkg/controllers/klusterlet/controllers/autoimportcontroller"
 	"github.com/stolostron/multicluster-controlplane/pkg/controllers/klusterlet/controllers/bootstrapcontroller"
+	"github.com/stolostron/multicluster-controlplane/pkg/controllers/klusterlet/controllers/importcontroller"
 	"github.com/stolostron/multicluster-controlplane/pkg/controllers/klusterlet/controllers/klusterletcontroller"
+	"github.com/stolostron/multicluster-controlplane/pkg/controllers/klusterlet/controllers/migrationcontroller"
+	"github.com/stolostron/multicluster-controlplane/pkg/controllers/klusterlet/controllers/pausecontroller"
 	"github.com/stolostron/multicluster-controlplane/pkg/controllers/klusterlet/controllers/ssarcontroller"
 	"github.com/stolostron/multicluster-controlplane/pkg/controllers/klusterlet/controllers/statuscontroller"
 )
@@ -26,17 +34,27 @@ import (
 type Klusterlet struct {
 	klusterletController factory.Controller
 	cleanupController    factory.Controller
+	tokenController      factory.Controller
 	statusController     factory.Controller
 	ssarController       factory.Controller
 	bootstrapController  factory.Controller
+	autoImportController factory.Controller
+	importController     factory.Controller
+	migrationController  factory.Controller
+	pauseController      factory.Controller
 }
 
 func (k *Klusterlet) Start(ctx context.Context) {
 	go k.klusterletController.Run(ctx, 1)
 	go k.cleanupController.Run(ctx, 1)
+	go k.tokenController.Run(ctx, 1)
 	go k.statusController.Run(ctx, 1)
 	go k.ssarController.Run(ctx, 1)
 	go k.bootstrapController.Run(ctx, 1)
+	go k.autoImportController.Run(ctx, 1)
+	go k.importController.Run(ctx, 1)
+	go k.migrationController.Run(ctx, 1)
+	go k.pauseController.Run(ctx, 1)
 }
 
 func NewKlusterlet(
@@ -50,6 +68,11 @@ func NewKlusterlet(
 	appliedManifestWorkClient workv1client.AppliedManifestWorkInterface,
 	kubeInformerFactory informers.SharedInformerFactory,
 	klusterletInformer operatorv1informers.KlusterletInformer,
+	controlplaneSecretInformer coreinformers.SecretInformer,
+	managedClusterInformer clusterv1informers.ManagedClusterInformer,
+	controlplaneRBACInformers rbacinformers.Interface,
+	evictionGracePeriod time.Duration,
+	managedClusterTokenTTL time.Duration,
 ) *Klusterlet {
 	recorder := util.NewLoggingRecorder("klusterlet-controller")
 	return &Klusterlet{
@@ -62,6 +85,7 @@ func NewKlusterlet(
 			kubeInformerFactory.Core().V1().Secrets(),
 			kubeInformerFactory.Apps().V1().Deployments(),
 			appliedManifestWorkClient,
+			managedClusterTokenTTL,
 			recorder,
 		),
 		cleanupController: klusterletcontroller.NewKlusterletCleanupController(
@@ -76,6 +100,17 @@ func NewKlusterlet(
 			kubeInformerFactory.Core().V1().Secrets(),
 			kubeInformerFactory.Apps().V1().Deployments(),
 			appliedManifestWorkClient,
+			evictionGracePeriod,
+			recorder,
+		),
+		tokenController: klusterletcontroller.NewManagedClusterTokenController(
+			kubeClient,
+			controlplaneKubeClient,
+			controlplaneAPIExtensionClient,
+			klusterletClient,
+			klusterletInformer,
+			appliedManifestWorkClient,
+			managedClusterTokenTTL,
 			recorder,
 		),
 		statusController: statuscontroller.NewKlusterletStatusController(
@@ -90,13 +125,47 @@ func NewKlusterlet(
 			klusterletClient,
 			klusterletInformer,
 			kubeInformerFactory.Core().V1().Secrets(),
+			controlplaneRBACInformers,
 			recorder,
 		),
 		bootstrapController: bootstrapcontroller.NewBootstrapController(
 			kubeClient,
+			klusterletClient,
 			klusterletInformer,
 			kubeInformerFactory.Core().V1().Secrets(),
 			recorder,
 		),
+		autoImportController: autoimportcontroller.NewAutoImportController(
+			controlplaneKubeClient,
+			controlplaneClusterClient,
+			klusterletClient,
+			controlplaneSecretInformer,
+			recorder,
+		),
+		importController: importcontroller.NewImportController(
+			controlplaneKubeClient,
+			controlplaneWorkClient,
+			kubeClient,
+			managedClusterInformer,
+			klusterletInformer,
+			recorder,
+		),
+		migrationController: migrationcontroller.NewMigrationController(
+			kubeClient,
+			controlplaneKubeClient,
+			controlplaneDynamicClient,
+			controlplaneClusterClient,
+			controlplaneWorkClient,
+			klusterletClient,
+			klusterletInformer,
+			recorder,
+		),
+		pauseController: pausecontroller.NewPauseController(
+			kubeClient,
+			controlplaneDynamicClient,
+			klusterletClient,
+			klusterletInformer,
+			recorder,
+		),
 	}
 }
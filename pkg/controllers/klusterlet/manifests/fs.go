@@ -5,3 +5,8 @@ import "embed"
 //go:embed klusterlet/management
 //go:embed klusterlet/managed
 var KlusterletManifestFiles embed.FS
+
+// ImportManifestFiles are the manifests to deploy the agent on a managed cluster in the default mode
+//
+//go:embed import
+var ImportManifestFiles embed.FS
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+)
+
+const (
+	leaseName          = "multicluster-controlplane-controllers"
+	leaseDuration      = 15 * time.Second
+	leaseRenewDeadline = 10 * time.Second
+	leaseRetryPeriod   = 2 * time.Second
+)
+
+// runWithLeaderElection runs the given function only when the current controlplane holds the controllers lease,
+// the lease is stored in the controlplane itself. When the leadership is lost, the context of the function is
+// cancelled and the controlplane continues to contend for the lease until the given context is done, so another
+// replica takes over the controllers without restarting the apiserver.
+func runWithLeaderElection(ctx context.Context, kubeClient kubernetes.Interface, run func(ctx context.Context)) error {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	identity := fmt.Sprintf("%s_%s", hostname, uuid.NewUUID())
+
+	lock, err := resourcelock.New(
+		resourcelock.LeasesResourceLock,
+		metav1.NamespaceSystem,
+		leaseName,
+		kubeClient.CoreV1(),
+		kubeClient.CoordinationV1(),
+		resourcelock.ResourceLockConfig{Identity: identity},
+	)
+	if err != nil {
+		return err
+	}
+
+	// the controllers of the previous term may be still stopping when the lease is acquired again, this makes
+	// sure that there is only one term is running.
+	var running sync.Mutex
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   leaseRenewDeadline,
+		RetryPeriod:     leaseRetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            leaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(leaderCtx context.Context) {
+				running.Lock()
+				defer running.Unlock()
+
+				klog.Infof("%s became the leader of the controlplane controllers", identity)
+				run(leaderCtx)
+			},
+			OnStoppedLeading: func() {
+				klog.Infof("%s stopped leading the controlplane controllers", identity)
+			},
+			OnNewLeader: func(current string) {
+				if current != identity {
+					klog.Infof("the controlplane controllers are led by %s", current)
+				}
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			elector.Run(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			default:
+				klog.Info("the controlplane controllers lease is lost, contending for it again")
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,185 @@
+// Copyright Contributors to the Open Cluster Management project
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	operatorapiv1 "open-cluster-management.io/api/operator/v1"
+	policyv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"open-cluster-management.io/governance-policy-propagator/controllers/common"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const collectTimeout = 10 * time.Second
+
+var (
+	lock                sync.Mutex
+	registeredCollector *controlplaneCollector
+)
+
+var (
+	managedClustersDesc = prometheus.NewDesc(
+		"multicluster_controlplane_managed_clusters",
+		"The number of managed clusters on the controlplane partitioned by the availability state.",
+		[]string{"state"}, nil,
+	)
+	policiesDesc = prometheus.NewDesc(
+		"multicluster_controlplane_policies",
+		"The number of root policies on the controlplane partitioned by the compliance state.",
+		[]string{"compliance"}, nil,
+	)
+	klusterletsDesc = prometheus.NewDesc(
+		"multicluster_controlplane_klusterlets",
+		"The number of klusterlets on the controlplane partitioned by the condition type and status.",
+		[]string{"condition", "status"}, nil,
+	)
+)
+
+// RegisterControlplaneCollector registers the controlplane collector to the controller-runtime registry, the
+// collector reads the managed clusters, policies and klusterlets from the given (cached) client when the
+// metrics are scraped.
+func RegisterControlplaneCollector(c client.Reader) error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	collector := &controlplaneCollector{client: c}
+	if err := ctrlmetrics.Registry.Register(collector); err != nil {
+		return err
+	}
+	registeredCollector = collector
+	return nil
+}
+
+// UnregisterControlplaneCollector unregisters the registered controlplane collector from the controller-runtime
+// registry.
+func UnregisterControlplaneCollector() {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if registeredCollector == nil {
+		return
+	}
+	ctrlmetrics.Registry.Unregister(registeredCollector)
+	registeredCollector = nil
+}
+
+type controlplaneCollector struct {
+	client client.Reader
+}
+
+func (c *controlplaneCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- managedClustersDesc
+	ch <- policiesDesc
+	ch <- klusterletsDesc
+}
+
+func (c *controlplaneCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	c.collectManagedClusters(ctx, ch)
+	c.collectPolicies(ctx, ch)
+	c.collectKlusterlets(ctx, ch)
+}
+
+func (c *controlplaneCollector) collectManagedClusters(ctx context.Context, ch chan<- prometheus.Metric) {
+	clusters := &clusterv1.ManagedClusterList{}
+	if err := c.client.List(ctx, clusters); err != nil {
+		klog.Errorf("failed to list managed clusters for metrics, %v", err)
+		return
+	}
+
+	states := map[string]float64{"available": 0, "unavailable": 0, "unknown": 0}
+	for _, cluster := range clusters.Items {
+		states[availabilityState(cluster.Status.Conditions)]++
+	}
+
+	for state, count := range states {
+		ch <- prometheus.MustNewConstMetric(managedClustersDesc, prometheus.GaugeValue, count, state)
+	}
+}
+
+func (c *controlplaneCollector) collectPolicies(ctx context.Context, ch chan<- prometheus.Metric) {
+	policies := &policyv1.PolicyList{}
+	if err := c.client.List(ctx, policies); err != nil {
+		if meta.IsNoMatchError(err) {
+			// the policy addon is disabled
+			return
+		}
+		klog.Errorf("failed to list policies for metrics, %v", err)
+		return
+	}
+
+	states := map[string]float64{
+		string(policyv1.Compliant):    0,
+		string(policyv1.NonCompliant): 0,
+		string(policyv1.Pending):      0,
+		"Unknown":                     0,
+	}
+	for _, policy := range policies.Items {
+		// the replicated policies in the cluster namespaces are not counted
+		if _, ok := policy.Labels[common.RootPolicyLabel]; ok {
+			continue
+		}
+
+		state := string(policy.Status.ComplianceState)
+		if len(state) == 0 {
+			state = "Unknown"
+		}
+		states[state]++
+	}
+
+	for state, count := range states {
+		ch <- prometheus.MustNewConstMetric(policiesDesc, prometheus.GaugeValue, count, state)
+	}
+}
+
+func (c *controlplaneCollector) collectKlusterlets(ctx context.Context, ch chan<- prometheus.Metric) {
+	klusterlets := &operatorapiv1.KlusterletList{}
+	if err := c.client.List(ctx, klusterlets); err != nil {
+		klog.Errorf("failed to list klusterlets for metrics, %v", err)
+		return
+	}
+
+	type conditionKey struct {
+		condition string
+		status    string
+	}
+	conditions := map[conditionKey]float64{}
+	for _, klusterlet := range klusterlets.Items {
+		for _, cond := range klusterlet.Status.Conditions {
+			conditions[conditionKey{condition: cond.Type, status: string(cond.Status)}]++
+		}
+	}
+
+	for key, count := range conditions {
+		ch <- prometheus.MustNewConstMetric(klusterletsDesc, prometheus.GaugeValue, count, key.condition, key.status)
+	}
+}
+
+func availabilityState(conditions []metav1.Condition) string {
+	for _, cond := range conditions {
+		if cond.Type != clusterv1.ManagedClusterConditionAvailable {
+			continue
+		}
+
+		switch cond.Status {
+		case metav1.ConditionTrue:
+			return "available"
+		case metav1.ConditionFalse:
+			return "unavailable"
+		}
+	}
+	return "unknown"
+}
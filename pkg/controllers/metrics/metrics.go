@@ -0,0 +1,110 @@
+// Copyright Contributors to the Open Cluster Management project
+package metrics
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// bridgedMetricPrefixes are the metric families of the controller-runtime registry that will be exposed by
+// the controlplane apiserver /metrics endpoint.
+var bridgedMetricPrefixes = []string{
+	"controller_runtime_",
+	"workqueue_",
+	"multicluster_controlplane_",
+}
+
+var registerOnce sync.Once
+
+// Register exposes the metrics of the embedded controller-runtime manager through the controlplane apiserver
+// /metrics endpoint, the controller-runtime registry is bridged into the legacy registry of the apiserver.
+func Register() {
+	registerOnce.Do(func() {
+		legacyregistry.RawMustRegister(&gathererCollector{
+			gatherer: ctrlmetrics.Registry,
+			prefixes: bridgedMetricPrefixes,
+		})
+	})
+}
+
+// gathererCollector is an unchecked prometheus collector that re-exposes the metric families of a gatherer.
+type gathererCollector struct {
+	gatherer prometheus.Gatherer
+	prefixes []string
+}
+
+// Describe sends no descriptors, this makes the collector to be an unchecked collector.
+func (c *gathererCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (c *gathererCollector) Collect(ch chan<- prometheus.Metric) {
+	families, err := c.gatherer.Gather()
+	if err != nil {
+		klog.Errorf("failed to gather controller metrics, %v", err)
+	}
+
+	for _, family := range families {
+		if !c.bridged(family.GetName()) {
+			continue
+		}
+
+		for _, m := range family.GetMetric() {
+			metric, err := toConstMetric(family, m)
+			if err != nil {
+				klog.Errorf("failed to convert controller metric %s, %v", family.GetName(), err)
+				continue
+			}
+			ch <- metric
+		}
+	}
+}
+
+func (c *gathererCollector) bridged(name string) bool {
+	for _, prefix := range c.prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func toConstMetric(family *dto.MetricFamily, m *dto.Metric) (prometheus.Metric, error) {
+	labelNames := make([]string, 0, len(m.GetLabel()))
+	labelValues := make([]string, 0, len(m.GetLabel()))
+	for _, label := range m.GetLabel() {
+		labelNames = append(labelNames, label.GetName())
+		labelValues = append(labelValues, label.GetValue())
+	}
+
+	desc := prometheus.NewDesc(family.GetName(), family.GetHelp(), labelNames, nil)
+
+	switch family.GetType() {
+	case dto.MetricType_COUNTER:
+		return prometheus.NewConstMetric(desc, prometheus.CounterValue, m.GetCounter().GetValue(), labelValues...)
+	case dto.MetricType_GAUGE:
+		return prometheus.NewConstMetric(desc, prometheus.GaugeValue, m.GetGauge().GetValue(), labelValues...)
+	case dto.MetricType_HISTOGRAM:
+		buckets := map[float64]uint64{}
+		for _, bucket := range m.GetHistogram().GetBucket() {
+			buckets[bucket.GetUpperBound()] = bucket.GetCumulativeCount()
+		}
+		return prometheus.NewConstHistogram(desc,
+			m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum(), buckets, labelValues...)
+	case dto.MetricType_SUMMARY:
+		quantiles := map[float64]float64{}
+		for _, quantile := range m.GetSummary().GetQuantile() {
+			quantiles[quantile.GetQuantile()] = quantile.GetValue()
+		}
+		return prometheus.NewConstSummary(desc,
+			m.GetSummary().GetSampleCount(), m.GetSummary().GetSampleSum(), quantiles, labelValues...)
+	default:
+		return prometheus.NewConstMetric(desc, prometheus.UntypedValue, m.GetUntyped().GetValue(), labelValues...)
+	}
+}
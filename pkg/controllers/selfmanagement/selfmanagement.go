@@ -19,10 +19,11 @@ import (
 	"open-cluster-management.io/multicluster-controlplane/pkg/util"
 
 	"github.com/stolostron/multicluster-controlplane/pkg/agent"
+	"github.com/stolostron/multicluster-controlplane/pkg/config"
 	"github.com/stolostron/multicluster-controlplane/pkg/feature"
 )
 
-func InstallControllers(options *options.ServerRunOptions) func(<-chan struct{}, *aggregatorapiserver.Config) error {
+func InstallControllers(options *options.ServerRunOptions, addOnsConfig *config.AddOnsConfig) func(<-chan struct{}, *aggregatorapiserver.Config) error {
 	return func(stopCh <-chan struct{}, aggregatorConfig *aggregatorapiserver.Config) error {
 		if _, err := rest.InClusterConfig(); err != nil {
 			klog.Warning("Current runtime environment is not in a cluster, ignore --self-management flag.")
@@ -66,7 +67,8 @@ func InstallControllers(options *options.ServerRunOptions) func(<-chan struct{},
 			agentOptions := agent.NewAgentOptions().
 				WithHubKubeConfig(hubRestConfig).
 				WithClusterName(clusterName).
-				WithSelfManagementEnabled(true)
+				WithSelfManagementEnabled(true).
+				WithAddOnsConfig(addOnsConfig)
 
 			klog.Info("starting addon agents")
 			if err := agentOptions.RunAddOns(ctx); err != nil {
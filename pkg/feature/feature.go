@@ -12,9 +12,15 @@ const (
 	// ManagedClusterInfo will start new controllers in the controlplane agent process to manage the managed cluster info in cluster namespace.
 	// It depends on the ClusterClaim feature.
 	ManagedClusterInfo featuregate.Feature = "ManagedClusterInfo"
+
+	// CertificatePolicy will start a new controller in the controlplane agent process to evaluate the certificate
+	// policies against the TLS secrets of the managed cluster.
+	// It depends on the ConfigurationPolicy feature.
+	CertificatePolicy featuregate.Feature = "CertificatePolicy"
 )
 
 var DefaultControlPlaneFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
 	ConfigurationPolicy: {Default: true, PreRelease: featuregate.Alpha},
 	ManagedClusterInfo:  {Default: true, PreRelease: featuregate.Alpha},
+	CertificatePolicy:   {Default: false, PreRelease: featuregate.Alpha},
 }
@@ -9,6 +9,7 @@ import (
 
 	"github.com/openshift/library-go/pkg/assets"
 	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	clusterinfov1beta1 "github.com/stolostron/cluster-lifecycle-api/clusterinfo/v1beta1"
 
 	"k8s.io/apiextensions-apiserver/pkg/apihelpers"
 	crdv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
@@ -24,6 +25,37 @@ import (
 	"open-cluster-management.io/multicluster-controlplane/pkg/util"
 )
 
+// the node role labels, the nodes with the worker label or without the control plane and infra labels are worker nodes
+const (
+	LabelNodeRoleOldControlPlane = "node-role.kubernetes.io/master"
+	LabelNodeRoleControlPlane    = "node-role.kubernetes.io/control-plane"
+	LabelNodeRoleInfra           = "node-role.kubernetes.io/infra"
+	LabelNodeRoleWorker          = "node-role.kubernetes.io/worker"
+)
+
+// the versions of the node that are reported with the labels of the node in the NodeList of the ManagedClusterInfo
+const (
+	NodeLabelKubeletVersion          = "node.open-cluster-management.io/kubelet-version"
+	NodeLabelContainerRuntimeVersion = "node.open-cluster-management.io/container-runtime-version"
+)
+
+// the conditions of the ManagedClusterInfo that are reported by the agent, they are kept when the controlplane syncs
+// the conditions of the ManagedCluster to the ManagedClusterInfo.
+const (
+	// ConditionKubernetesDistribution reports the distribution information of the non-OCP managed cluster, the reason
+	// is the inferred distribution, e.g. EKS, AKS, GKE, K3s, RKE2 and Vanilla.
+	ConditionKubernetesDistribution = "KubernetesDistribution"
+	// ConditionKubeVersionSkewed is true if the kubelet versions of some nodes are not supported by the kube-apiserver.
+	ConditionKubeVersionSkewed = "KubeVersionSkewed"
+)
+
+// AgentClusterInfoConditions are the conditions of the ManagedClusterInfo that are reported by the agent.
+var AgentClusterInfoConditions = []string{
+	clusterinfov1beta1.ManagedClusterInfoSynced,
+	ConditionKubernetesDistribution,
+	ConditionKubeVersionSkewed,
+}
+
 func EnsureCRDs(ctx context.Context, scheme *runtime.Scheme, client apiextensionsclient.Interface, fs embed.FS, crds ...string) error {
 	crdMap := make(map[string]*crdv1.CustomResourceDefinition, len(crds))
 	for _, crdFileName := range crds {